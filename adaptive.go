@@ -0,0 +1,91 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockadaptive.lua
+var lockadaptivesrc string
+var lockadaptivescr = redis.NewScript(lockadaptivesrc)
+
+//go:embed unlockadaptive.lua
+var unlockadaptivesrc string
+var unlockadaptivescr = redis.NewScript(unlockadaptivesrc)
+
+// adaptiveTTL defines the TTL of locks applied by Locker.LockAdaptive.
+type adaptiveTTL struct {
+	base   time.Duration
+	max    time.Duration
+	factor float64
+}
+
+// WithAdaptiveTTL sets the TTL of locks applied by Locker.LockAdaptive, which grows when a lock
+// is applied again after it expired instead of being released, e.g. because its owner crashed:
+// the TTL is base multiplied by factor for each such time in a row, but not greater than max.
+func WithAdaptiveTTL(base, max time.Duration, factor float64) Option {
+	return func(locker *Locker) {
+		locker.adaptive = &adaptiveTTL{base: base, max: max, factor: factor}
+	}
+}
+
+// LockAdaptive creates and applies new lock with the TTL set by WithAdaptiveTTL, the lock is applied
+// with the base TTL, or a longer one if the previous lock of the key expired instead of being released.
+// The number of such expirations is counted in Redis with key "<key>:adaptive", which expires
+// the max TTL after the lock, and is reset when the lock is released by Unlock.
+// Lock.Lock of the returned lock extends it by the TTL the lock was applied with, ignoring its ttl argument.
+// Returns ErrNoDefaultTTL if WithAdaptiveTTL is not set, and ErrInvalidOption with WithReentrant,
+// which adaptive locks do not support.
+func (locker *Locker) LockAdaptive(ctx context.Context, key string) (LockResult, error) {
+	r := LockResult{}
+	if locker.reentrant {
+		return r, ErrInvalidOption
+	}
+	if locker.adaptive == nil {
+		return r, ErrNoDefaultTTL
+	}
+	value, err := locker.newValue(ctx, key)
+	if err != nil {
+		return r, err
+	}
+	r.Lock = locker.newLock(key, value)
+	r.adaptive = true
+	r.Result, err = r.Lock.applyAdaptive(ctx)
+	r.Lock.cleanup(ctx, err)
+	return r, err
+}
+
+// applyAdaptive applies the lock with the TTL set by WithAdaptiveTTL.
+func (lock *Lock) applyAdaptive(ctx context.Context) (Result, error) {
+	start := time.Now()
+	a := lock.locker.adaptive
+	key := lock.locker.redisKey(lock.key)
+	res, err := lock.locker.eval(ctx, "lock", lockadaptivescr, []string{key, adaptiveKey(key)},
+		lock.value, lock.locker.ms(a.base), lock.locker.ms(a.max), strconv.FormatFloat(a.factor, 'f', -1, 64))
+	if err != nil {
+		return Result(0), err
+	}
+	arr, ok := res.([]interface{})
+	if !ok || len(arr) != 2 {
+		return Result(0), ErrInvalidResponse
+	}
+	v, ok := arr[0].(int64)
+	if !ok {
+		return Result(0), ErrInvalidResponse
+	}
+	ttl, ok := arr[1].(int64)
+	if !ok {
+		return Result(0), ErrInvalidResponse
+	}
+	r := Result(v)
+	return r, lock.applied(ctx, key, r, time.Duration(ttl)*time.Millisecond, start)
+}
+
+// adaptiveKey creates Redis key of the counter of lock expirations.
+func adaptiveKey(key string) string {
+	return key + ":adaptive"
+}
@@ -0,0 +1,81 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockAdaptive(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key, adaptiveKey(key)).Err()
+	require.NoError(t, err)
+
+	_, err = NewLocker(client).LockAdaptive(ctx, key)
+	require.Equal(t, ErrNoDefaultTTL, err)
+
+	base := 100 * time.Millisecond
+	max := 300 * time.Millisecond
+	locker := NewLocker(client, WithAdaptiveTTL(base, max, 2))
+
+	pttl := func() time.Duration {
+		v, err := client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		return v
+	}
+
+	lr, err := locker.LockAdaptive(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+	require.True(t, pttl() <= base)
+
+	r, err := lr.Lock.Lock(ctx, time.Minute)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+	require.True(t, pttl() <= base)
+
+	time.Sleep(base + 50*time.Millisecond) // the lock expires instead of being released
+
+	lr, err = locker.LockAdaptive(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+	require.True(t, pttl() > base && pttl() <= 2*base)
+
+	lr2, err := locker.LockAdaptive(ctx, key)
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+
+	time.Sleep(2*base + 50*time.Millisecond)
+
+	lr, err = locker.LockAdaptive(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+	require.True(t, pttl() > 2*base && pttl() <= max)
+
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	n, err := client.Exists(ctx, key, adaptiveKey(key)).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	lr, err = locker.LockAdaptive(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+	require.True(t, pttl() <= base)
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+}
+
+func TestLockAdaptiveReentrant(t *testing.T) {
+	locker := NewLocker(&ClientMock{}, WithReentrant(), WithAdaptiveTTL(time.Second, time.Minute, 2))
+	_, err := locker.LockAdaptive(context.Background(), "key")
+	require.Equal(t, ErrInvalidOption, err)
+}
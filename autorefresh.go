@@ -0,0 +1,64 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// AutoRefresh extends the lock by the TTL it was applied with every interval in the background,
+// until the returned stop function is called or ctx is done. Errors of extending the lock are delivered
+// on the returned channel, if the lock is lost, i.e. it is not extended, ErrLockLost is delivered
+// and extending stops. If the lock had expired and is applied again, it is released, as it may have been
+// held by someone else meanwhile. The channel is closed when extending stops, the stop function waits for it.
+// Extending a lock which was never applied fails with ErrLockLost.
+// Returns ErrInvalidInterval if the interval is not positive.
+func (lock *Lock) AutoRefresh(ctx context.Context, interval time.Duration) (<-chan error, func(), error) {
+	if interval <= 0 {
+		return nil, nil, ErrInvalidInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	errs := make(chan error, 1)
+	l := *lock
+	go func() {
+		defer close(errs)
+		if l.ttl <= 0 {
+			errs <- ErrLockLost
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r, err := l.refresh(ctx, l.ttl)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				if !r.Extended() {
+					l.locker.lost(l.key)
+					if r.Acquired() {
+						uctx, ucancel := context.WithTimeout(context.Background(), l.ttl)
+						_, _ = l.Unlock(uctx)
+						ucancel()
+					}
+					errs <- ErrLockLost
+					return
+				}
+			}
+		}
+	}()
+	return errs, func() {
+		cancel()
+		for range errs {
+		}
+	}, nil
+}
@@ -0,0 +1,96 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoRefresh(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	var lost []string
+	locker := NewLocker(client, WithOnLost(func(key string) {
+		lost = append(lost, key)
+	}))
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	_, _, err = lr.AutoRefresh(ctx, 0)
+	require.Equal(t, ErrInvalidInterval, err)
+
+	errs, stop, err := lr.AutoRefresh(ctx, ttl/2)
+	require.NoError(t, err)
+	time.Sleep(3 * ttl) // the lock is extended in the background
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, lr.Token(), v)
+	require.Len(t, errs, 0)
+	stop()
+	_, ok := <-errs
+	require.False(t, ok)
+	require.Len(t, lost, 0)
+
+	errs, stop, err = lr.AutoRefresh(ctx, ttl/2)
+	require.NoError(t, err)
+	defer stop()
+	err = client.Set(ctx, key, "value", ttl).Err()
+	require.NoError(t, err)
+	select {
+	case err = <-errs:
+		require.Equal(t, ErrLockLost, err)
+	case <-time.After(ttl):
+		t.Fatal("the lock is not lost")
+	}
+	_, ok = <-errs
+	require.False(t, ok)
+	require.Equal(t, []string{key}, lost)
+
+	lr, err = locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	errs, stop, err = lr.AutoRefresh(ctx, ttl/2)
+	require.NoError(t, err)
+	defer stop()
+	require.Equal(t, ErrLockLost, <-errs)
+}
+
+func TestAutoRefreshReacquired(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client)
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	errs, stop, err := lr.AutoRefresh(ctx, 2*ttl) // the lock expires before it is extended
+	require.NoError(t, err)
+	defer stop()
+	select {
+	case err = <-errs:
+		require.Equal(t, ErrLockLost, err)
+	case <-time.After(4 * ttl):
+		t.Fatal("the lock is not lost")
+	}
+	n, err := client.Exists(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n) // the lock applied again is released
+}
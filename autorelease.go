@@ -0,0 +1,22 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// LockAutoRelease creates and applies new lock, if the lock is applied starts a timer which releases it
+// after releaseAfter even if Unlock is never called, e.g. to avoid locks held until TTL is over because
+// of forgotten Unlock calls during development. The returned func stops the timer.
+func (locker *Locker) LockAutoRelease(ctx context.Context, key string, ttl time.Duration, releaseAfter time.Duration) (LockResult, func(), error) {
+	lr, err := locker.Lock(ctx, key, ttl)
+	if err != nil || !lr.OK() {
+		return lr, func() {}, err
+	}
+	timer := time.AfterFunc(releaseAfter, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ttl)
+		defer cancel()
+		_, _ = lr.Unlock(ctx)
+	})
+	return lr, func() { timer.Stop() }, nil
+}
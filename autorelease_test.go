@@ -0,0 +1,53 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockAutoRelease(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 10 * time.Second
+	releaseAfter := 100 * time.Millisecond
+	locker := NewLocker(client)
+
+	lr, cancel, err := locker.LockAutoRelease(ctx, key, ttl, releaseAfter)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	defer cancel()
+
+	lr2, cancel2, err := locker.LockAutoRelease(ctx, key, ttl, releaseAfter)
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+	cancel2()
+
+	time.Sleep(2 * releaseAfter) // wait for the lock is released
+
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+
+	lr, cancel, err = locker.LockAutoRelease(ctx, key, ttl, releaseAfter)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	cancel()
+
+	time.Sleep(2 * releaseAfter)
+
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, lr.value, v)
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
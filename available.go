@@ -0,0 +1,67 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed pttl.lua
+var pttlsrc string
+var pttlscr = redis.NewScript(pttlsrc)
+
+// subscribeClient is redis client interface for subscribing to channels.
+type subscribeClient interface {
+	PSubscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// WaitAvailable returns the channel which is closed when the key is not held by anyone,
+// so a lock can be applied then using a custom strategy. The channel is never closed if ctx is done before,
+// so receiving from it should be combined with ctx.Done().
+// The key is polled when the TTL of the held lock is over, but at least once a second.
+// If the client implements PSubscribe, keyspace notifications of the key are also received to poll
+// as soon as the key changes, which requires Redis configured with notify-keyspace-events,
+// e.g. "Kg$x" for keyspace events of generic and string commands and expiration.
+// Polling and the subscription are stopped when the channel is closed or ctx is done.
+func (locker *Locker) WaitAvailable(ctx context.Context, key string) <-chan struct{} {
+	ch := make(chan struct{})
+	go locker.waitAvailable(ctx, locker.redisKey(key), ch)
+	return ch
+}
+
+func (locker *Locker) waitAvailable(ctx context.Context, key string, ch chan struct{}) {
+	var events <-chan *redis.Message
+	if client, ok := locker.client.(subscribeClient); ok {
+		pubsub := client.PSubscribe(ctx, "__keyspace@*__:"+globEscaper.Replace(key))
+		defer pubsub.Close()
+		events = pubsub.Channel()
+	}
+	for {
+		d := errorRetryDelay
+		pttl, err := locker.run(ctx, "poll", pttlscr, []string{key})
+		if err == nil {
+			if pttl == -2 {
+				close(ch)
+				return
+			}
+			d = time.Duration(pttl) * time.Millisecond
+			if d < 0 || d > maxWait {
+				d = maxWait
+			}
+			if d == 0 {
+				d = minWait
+			}
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-events:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
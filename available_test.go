@@ -0,0 +1,53 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitAvailable(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client)
+
+	select {
+	case <-locker.WaitAvailable(ctx, key):
+	case <-time.After(ttl):
+		t.Fatal("the key is not available")
+	}
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	start := time.Now()
+	select {
+	case <-locker.WaitAvailable(ctx, key):
+		require.True(t, time.Since(start) >= ttl/2)
+	case <-time.After(3 * ttl):
+		t.Fatal("the key is not available")
+	}
+
+	lr, err = locker.Lock(ctx, key, time.Second)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	ctx1, cancel := context.WithTimeout(ctx, ttl)
+	defer cancel()
+	select {
+	case <-locker.WaitAvailable(ctx1, key):
+		t.Fatal("the key is available")
+	case <-ctx1.Done():
+	}
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+}
@@ -0,0 +1,80 @@
+package locker
+
+import (
+	"sync"
+	"time"
+)
+
+// contention is the number of times a key was contended since the start of the window.
+type contention struct {
+	start time.Time
+	count int
+}
+
+// breaker fails applying locks of keys which are contended too often without calling Redis.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	keys      map[string]*contention
+}
+
+// WithContentionCircuitBreaker sets locker to fail applying a lock of the key with ErrCircuitOpen without calling Redis,
+// if the lock of the key was held by someone else threshold times within the window, by any goroutine of the process.
+// The count is reset when the window is over or the lock of the key is applied.
+// It applies to Locker.Lock and Locker.LockWait, which returns the error instead of waiting.
+func WithContentionCircuitBreaker(threshold int, window time.Duration) Option {
+	return func(locker *Locker) {
+		locker.breaker = &breaker{threshold: threshold, window: window, keys: make(map[string]*contention)}
+	}
+}
+
+// allow reports whether applying the lock of the key may call Redis.
+func (b *breaker) allow(key string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.keys[key]
+	if !ok {
+		return true
+	}
+	if time.Since(c.start) > b.window {
+		delete(b.keys, key)
+		return true
+	}
+	return c.count < b.threshold
+}
+
+// record counts the result of applying the lock of the key.
+func (b *breaker) record(key string, r Result) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.OK() {
+		delete(b.keys, key)
+		return
+	}
+	now := time.Now()
+	c, ok := b.keys[key]
+	if !ok || now.Sub(c.start) > b.window {
+		b.prune(now)
+		c = &contention{start: now}
+		b.keys[key] = c
+	}
+	c.count++
+}
+
+// prune removes keys which windows are over.
+func (b *breaker) prune(now time.Time) {
+	for key, c := range b.keys {
+		if now.Sub(c.start) > b.window {
+			delete(b.keys, key)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContentionCircuitBreaker(t *testing.T) {
+	clientMock := &ClientMock{}
+	window := 100 * time.Millisecond
+	locker := NewLocker(clientMock, WithContentionCircuitBreaker(2, window))
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(42), nil)).Times(2)
+
+	for i := 0; i < 2; i++ {
+		lr, err := locker.Lock(ctx, key, ttl)
+		require.NoError(t, err)
+		require.False(t, lr.OK())
+	}
+	_, err := locker.Lock(ctx, key, ttl)
+	require.Equal(t, ErrCircuitOpen, err)
+	_, err = locker.LockWait(ctx, key, ttl)
+	require.Equal(t, ErrCircuitOpen, err)
+	clientMock.AssertExpectations(t)
+
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{"key1"}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	lr, err := locker.Lock(ctx, "key1", ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	time.Sleep(window + 10*time.Millisecond)
+
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	lr, err = locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	require.Len(t, locker.breaker.keys, 0)
+	clientMock.AssertExpectations(t)
+}
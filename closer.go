@@ -0,0 +1,42 @@
+package locker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Closer contains new lock and result of applying a lock, it implements io.Closer releasing the lock.
+type Closer struct {
+	LockResult
+	ttl  time.Duration
+	once sync.Once
+	err  error
+}
+
+// LockCloser creates and applies new lock as Locker.Lock does, returns the lock which is released by Close:
+//
+//	lk, err := lkr.LockCloser(ctx, key, ttl)
+//	if err != nil {
+//		return err
+//	}
+//	defer lk.Close()
+func (locker *Locker) LockCloser(ctx context.Context, key string, ttl time.Duration) (*Closer, error) {
+	lr, err := locker.Lock(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &Closer{LockResult: lr, ttl: ttl}, nil
+}
+
+// Close releases the lock using a background context with timeout equal to the lock TTL,
+// because the lock expires after that anyway. It is safe to call Close more than once,
+// each call returns the error of releasing the lock, if any.
+func (c *Closer) Close() error {
+	c.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.ttl)
+		defer cancel()
+		_, c.err = c.Unlock(ctx)
+	})
+	return c.err
+}
@@ -0,0 +1,46 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockCloser(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	lk, err := locker.LockCloser(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lk.OK())
+	var closer io.Closer = lk
+	require.NoError(t, closer.Close())
+	require.NoError(t, closer.Close())
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+
+	clientMock := &ClientMock{}
+	locker = NewLocker(clientMock)
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil))
+	clientMock.On("EvalSha", mock.Anything, unlockscr.Hash(), []string{key}, mock.Anything).Return(redis.NewCmdResult(nil, e)).Once()
+	lk, err = locker.LockCloser(ctx, key, ttl)
+	require.NoError(t, err)
+	require.ErrorIs(t, lk.Close(), e)
+	require.ErrorIs(t, lk.Close(), e)
+	clientMock.AssertExpectations(t)
+}
@@ -0,0 +1,38 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockif.lua
+var lockifsrc string
+var lockifscr = redis.NewScript(lockifsrc)
+
+// LockIf creates and applies new lock only if the value of condKey equals condValue,
+// the condition is checked atomically with applying the lock.
+// If the condition fails the lock is not applied and Result.ConditionFailed is true.
+// The condKey is used as is, and with Redis Cluster it must hash to the same slot as the key.
+// Returns ErrInvalidOption with WithReentrant, which LockIf does not support.
+func (locker *Locker) LockIf(ctx context.Context, key string, ttl time.Duration, condKey string, condValue string) (LockResult, error) {
+	r := LockResult{}
+	if locker.reentrant {
+		return r, ErrInvalidOption
+	}
+	value, err := locker.newValue(ctx, key)
+	if err != nil {
+		return r, err
+	}
+	r.Lock = locker.newLock(key, value)
+	r.Result, err = r.Lock.apply(ctx, "lock", lockifscr, []string{locker.redisKey(key), condKey}, ttl, condValue)
+	r.Lock.cleanup(ctx, err)
+	return r, err
+}
+
+// ConditionFailed is the flag of failed condition of Locker.LockIf, the lock is not applied in this case.
+func (r Result) ConditionFailed() bool {
+	return r == ResultConditionFailed
+}
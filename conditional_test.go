@@ -0,0 +1,58 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockIf(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	condKey := "key:status"
+	err := client.Del(ctx, key, condKey).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	lr, err := locker.LockIf(ctx, key, ttl, condKey, "PENDING")
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	require.True(t, lr.ConditionFailed())
+
+	err = client.Set(ctx, condKey, "PENDING", 0).Err()
+	require.NoError(t, err)
+
+	lr, err = locker.LockIf(ctx, key, ttl, condKey, "PENDING")
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+	require.False(t, lr.ConditionFailed())
+
+	lr2, err := locker.LockIf(ctx, key, ttl, condKey, "PENDING")
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+	require.False(t, lr2.ConditionFailed())
+	require.True(t, lr2.TTL() > 0 && lr2.TTL() <= ttl)
+
+	err = client.Set(ctx, condKey, "DONE", 0).Err()
+	require.NoError(t, err)
+	lr2, err = locker.LockIf(ctx, key, ttl, condKey, "PENDING")
+	require.NoError(t, err)
+	require.True(t, lr2.ConditionFailed())
+
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestLockIfReentrant(t *testing.T) {
+	_, err := NewLocker(&ClientMock{}, WithReentrant()).LockIf(context.Background(), "key", time.Second, "cond", "value")
+	require.Equal(t, ErrInvalidOption, err)
+}
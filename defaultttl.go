@@ -0,0 +1,59 @@
+package locker
+
+import (
+	"context"
+	"path"
+	"sort"
+	"time"
+)
+
+// WithKeyTTL sets TTLs of locks applied by Locker.LockDefault. Keys of the map are either keys
+// or glob-style patterns as matched by path.Match, e.g. "cache:*". An exact key takes precedence,
+// otherwise the TTL of the longest matching pattern is used, of equally long patterns the first in lexical order.
+func WithKeyTTL(ttls map[string]time.Duration) Option {
+	return func(locker *Locker) {
+		locker.keyTTLs = ttls
+		locker.keyPatterns = make([]string, 0, len(ttls))
+		for pattern := range ttls {
+			locker.keyPatterns = append(locker.keyPatterns, pattern)
+		}
+		sort.Slice(locker.keyPatterns, func(i, j int) bool {
+			a, b := locker.keyPatterns[i], locker.keyPatterns[j]
+			if len(a) != len(b) {
+				return len(a) > len(b)
+			}
+			return a < b
+		})
+	}
+}
+
+// WithDefaultTTL sets the TTL of locks applied by Locker.LockDefault with keys which match none of
+// the keys set by WithKeyTTL.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(locker *Locker) {
+		locker.defaultTTL = ttl
+	}
+}
+
+// LockDefault creates and applies new lock as Lock does, with the TTL set for the key by WithKeyTTL
+// or WithDefaultTTL. Returns ErrNoDefaultTTL if no TTL is set for the key.
+func (locker *Locker) LockDefault(ctx context.Context, key string) (LockResult, error) {
+	ttl := locker.keyTTL(key)
+	if ttl <= 0 {
+		return LockResult{}, ErrNoDefaultTTL
+	}
+	return locker.Lock(ctx, key, ttl)
+}
+
+// keyTTL returns the TTL set for the key, or 0 if no TTL is set.
+func (locker *Locker) keyTTL(key string) time.Duration {
+	if ttl, ok := locker.keyTTLs[key]; ok {
+		return ttl
+	}
+	for _, pattern := range locker.keyPatterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return locker.keyTTLs[pattern]
+		}
+	}
+	return locker.defaultTTL
+}
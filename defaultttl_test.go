@@ -0,0 +1,67 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockDefault(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "cache:1"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	locker := NewLocker(client)
+	_, err = locker.LockDefault(ctx, key)
+	require.Equal(t, ErrNoDefaultTTL, err)
+
+	ttl := 500 * time.Millisecond
+	locker = NewLocker(client, WithKeyTTL(map[string]time.Duration{"cache:*": ttl}))
+	lr, err := locker.LockDefault(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	pttl, err := client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 0 && pttl <= ttl)
+}
+
+func TestKeyTTL(t *testing.T) {
+	locker := NewLocker(nil, WithDefaultTTL(time.Minute), WithKeyTTL(map[string]time.Duration{
+		"cache:*":       time.Second,
+		"cache:users:*": 2 * time.Second,
+		"cache:users:1": 3 * time.Second,
+		"migration":     time.Hour,
+	}))
+	tests := []struct {
+		key string
+		ttl time.Duration
+	}{
+		{"cache:1", time.Second},
+		{"cache:users:2", 2 * time.Second},
+		{"cache:users:1", 3 * time.Second},
+		{"migration", time.Hour},
+		{"job:1", time.Minute},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.ttl, locker.keyTTL(tc.key), tc.key)
+	}
+}
+
+func TestKeyTTLTie(t *testing.T) {
+	ttls := map[string]time.Duration{
+		"job:?:1": time.Second,
+		"job:1:?": 2 * time.Second,
+		"job:*":   3 * time.Second,
+	}
+	for i := 0; i < 100; i++ {
+		locker := NewLocker(nil, WithKeyTTL(ttls))
+		require.Equal(t, 2*time.Second, locker.keyTTL("job:1:1")) // "job:1:?" < "job:?:1"
+	}
+}
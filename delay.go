@@ -0,0 +1,21 @@
+package locker
+
+import (
+	"math/rand"
+	"time"
+)
+
+// defaultRetryDelay is the default delay between attempts of applying a lock by Locker.LockWithRetry.
+const defaultRetryDelay = 100 * time.Millisecond
+
+// defaultRetryJitter is the default maximum random addition to the delay between attempts.
+const defaultRetryJitter = 50 * time.Millisecond
+
+// newDelay returns the delay between attempts of applying a lock: retryDelay plus random jitter
+// not greater than retryJitter, so concurrent clients do not retry at the same time.
+func newDelay(retryDelay, retryJitter time.Duration) time.Duration {
+	if retryJitter <= 0 {
+		return retryDelay
+	}
+	return retryDelay + time.Duration(rand.Int63n(int64(retryJitter)+1))
+}
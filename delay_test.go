@@ -0,0 +1,16 @@
+package locker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDelay(t *testing.T) {
+	require.Equal(t, 100*time.Millisecond, newDelay(100*time.Millisecond, 0))
+	for i := 0; i < 100; i++ {
+		d := newDelay(100*time.Millisecond, 50*time.Millisecond)
+		require.True(t, d >= 100*time.Millisecond && d <= 150*time.Millisecond, d)
+	}
+}
@@ -0,0 +1,63 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockepoch.lua
+var lockepochsrc string
+var lockepochscr = redis.NewScript(lockepochsrc)
+
+//go:embed extendepoch.lua
+var extendepochsrc string
+var extendepochscr = redis.NewScript(extendepochsrc)
+
+//go:embed setepoch.lua
+var setepochsrc string
+var setepochscr = redis.NewScript(setepochsrc)
+
+// WithEpochKey sets locker to fence off stale generations of lock holders using the epoch stored
+// in Redis with the key: lock values are prefixed with the epoch set by Locker.SetEpoch as "<epoch>/",
+// and applying or extending a lock with an epoch older than the stored one fails with ErrStaleEpoch.
+// It applies to Lock.Lock, Locker.Lock, Locker.LockWait and Locker.RefreshByToken,
+// and takes precedence over WithGrowOnlyTTL. With Redis Cluster the key must hash to the same slot as lock keys.
+func WithEpochKey(key string) Option {
+	return func(locker *Locker) {
+		locker.epochKey = key
+	}
+}
+
+// SetEpoch stores the epoch in Redis if it is not older than the stored one, and sets it as the epoch
+// of locks created by the locker since then. Locks of all lockers with older epochs can not be applied
+// or extended anymore. Returns ErrStaleEpoch if the stored epoch is newer.
+// Requires WithEpochKey, otherwise only sets the epoch of the locker.
+func (locker *Locker) SetEpoch(ctx context.Context, epoch int64) error {
+	if locker.epochKey != "" {
+		if _, err := locker.eval(ctx, "epoch", setepochscr, []string{locker.epochKey}, epoch); err != nil {
+			return err
+		}
+	}
+	atomic.StoreInt64(&locker.epoch, epoch)
+	return nil
+}
+
+// epochValue prefixes the lock value with the epoch if WithEpochKey is set.
+func (locker *Locker) epochValue(value string) string {
+	if locker.epochKey == "" {
+		return value
+	}
+	return strconv.FormatInt(atomic.LoadInt64(&locker.epoch), 10) + "/" + value
+}
+
+// lockKeys returns keys of the scripts applying and extending the lock with the Redis key.
+func (locker *Locker) lockKeys(key string) []string {
+	if locker.epochKey != "" {
+		return []string{key, locker.epochKey}
+	}
+	return []string{key}
+}
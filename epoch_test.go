@@ -0,0 +1,62 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEpochKey(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	epochKey := "epoch"
+	err := client.Del(ctx, key, epochKey).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker1 := NewLocker(client, WithEpochKey(epochKey))
+	locker2 := NewLocker(client, WithEpochKey(epochKey))
+
+	lr1, err := locker1.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr1.OK())
+	require.True(t, strings.HasPrefix(lr1.value, "0/"))
+
+	err = locker2.SetEpoch(ctx, 1)
+	require.NoError(t, err)
+	v, err := client.Get(ctx, epochKey).Result()
+	require.NoError(t, err)
+	require.Equal(t, "1", v)
+
+	_, err = lr1.Lock.Lock(ctx, ttl)
+	require.True(t, errors.Is(err, ErrStaleEpoch))
+	_, err = locker1.RefreshByToken(ctx, lr1.value, []string{key}, ttl)
+	require.True(t, errors.Is(err, ErrStaleEpoch))
+	ok, err := lr1.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	lr2, err := locker2.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr2.OK())
+	require.True(t, strings.HasPrefix(lr2.value, "1/"))
+	r, err := lr2.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	err = locker1.SetEpoch(ctx, 0)
+	require.True(t, errors.Is(err, ErrStaleEpoch))
+	_, err = locker1.Lock(ctx, "key1", ttl)
+	require.True(t, errors.Is(err, ErrStaleEpoch))
+
+	_, err = lr2.Unlock(ctx)
+	require.NoError(t, err)
+}
@@ -0,0 +1,144 @@
+package locker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidResponse is the error returned when Redis command returns response of unexpected type.
+var ErrInvalidResponse = errors.New("locker: unexpected redis response")
+
+// ErrUnexpectedRedisResponse is an alias of ErrInvalidResponse kept for compatibility.
+var ErrUnexpectedRedisResponse = ErrInvalidResponse
+
+// ErrKeyNameClash is the error returned when Redis key exists and has no TTL.
+var ErrKeyNameClash = errors.New("locker: key name clash")
+
+// ErrInvalidValue is the error returned when lock value is empty or too long.
+var ErrInvalidValue = errors.New("locker: invalid value")
+
+// ErrLockLost is the error returned when a lock expired or was applied by someone else while being held.
+var ErrLockLost = errors.New("locker: lock lost")
+
+// ErrAcquireVerificationFailed is the error returned when the value of an applied lock read back
+// does not match the lock value.
+var ErrAcquireVerificationFailed = errors.New("locker: acquire verification failed")
+
+// ErrReadOnly is the error returned when Redis rejects writes because it is a read-only replica.
+var ErrReadOnly = errors.New("locker: redis is read-only")
+
+// ErrOperationTimeout is the error returned when a Redis round trip takes longer than the timeout
+// set by WithOperationTimeout, while the context of the operation is not done.
+var ErrOperationTimeout = errors.New("locker: operation timeout")
+
+// ErrNoDefaultTTL is the error returned by Locker.LockDefault when no TTL is set for the key.
+var ErrNoDefaultTTL = errors.New("locker: no default ttl")
+
+// ErrLocked is the error matching LockedError returned by Result.Err when the lock is held by someone else.
+var ErrLocked = errors.New("locker: locked")
+
+// ErrConditionFailed is the error returned by Result.Err when the condition of Locker.LockIf fails.
+var ErrConditionFailed = errors.New("locker: condition failed")
+
+// ErrNotApplied is the error returned by Result.Err when the lock of a key is not applied
+// because a lock of another key is held, see Locker.LockManyTTL.
+var ErrNotApplied = errors.New("locker: not applied")
+
+// LockedError is the error returned by Result.Err when the lock is held by someone else.
+type LockedError struct {
+	// TTL of the lock held by someone else.
+	TTL time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ErrLocked.Error() + " for " + e.TTL.String()
+}
+
+// Is reports whether the target is ErrLocked.
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// ErrStaleEpoch is the error returned when the epoch of a lock is older than the epoch stored in Redis,
+// see WithEpochKey.
+var ErrStaleEpoch = errors.New("locker: stale epoch")
+
+// ErrCircuitOpen is the error returned when applying a lock fails without calling Redis
+// because the key is contended too often, see WithContentionCircuitBreaker.
+var ErrCircuitOpen = errors.New("locker: circuit open")
+
+// ErrRedisOOM is the error returned when Redis rejects writes because it reached maxmemory
+// with the noeviction policy.
+var ErrRedisOOM = errors.New("locker: redis is out of memory")
+
+// ErrHeldLocally is the error returned by Locker.Lock when the lock is applied by a concurrent caller
+// within the process, see WithSingleflight.
+var ErrHeldLocally = errors.New("locker: held locally")
+
+// ErrInvalidOption is the error returned by NewLockerContext when an option value is invalid,
+// e.g. negative retry count or delay.
+var ErrInvalidOption = errors.New("locker: invalid option")
+
+// ErrUnsupported is the error returned by a locker created by NewLockerGateway
+// when the operation needs Redis, which the gateway does not provide.
+var ErrUnsupported = errors.New("locker: unsupported by gateway")
+
+// ErrInvalidLimit is the error returned by Locker.NewSemaphore when the limit of holders is less than 1.
+var ErrInvalidLimit = errors.New("locker: invalid limit")
+
+// ErrInvalidInterval is the error returned by Lock.AutoRefresh when the interval is not positive.
+var ErrInvalidInterval = errors.New("locker: invalid interval")
+
+// ErrInvalidTTL is the error returned by a Gateway when the TTL is not positive.
+var ErrInvalidTTL = errors.New("locker: invalid ttl")
+
+// ErrNoClients is the error returned by NewRedLocker when there are no clients.
+var ErrNoClients = errors.New("locker: no clients")
+
+// ErrNoKeys is the error returned by Locker.Prepare when there are no keys.
+var ErrNoKeys = errors.New("locker: no keys")
+
+// ErrDeadlinePassed is the error returned by Lock.LockUntil when the deadline is over.
+var ErrDeadlinePassed = errors.New("locker: deadline passed")
+
+// redisError is Redis error classified as one of package errors.
+type redisError struct {
+	kind error
+	err  error
+}
+
+func (e *redisError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+// Is reports whether the error is classified as target.
+func (e *redisError) Is(target error) bool {
+	return e.kind == target
+}
+
+// Unwrap returns the original Redis error.
+func (e *redisError) Unwrap() error {
+	return e.err
+}
+
+// classify wraps Redis error into one of package errors if possible.
+func classify(err error) error {
+	if strings.HasPrefix(err.Error(), "READONLY ") {
+		return &redisError{kind: ErrReadOnly, err: err}
+	}
+	if strings.HasPrefix(err.Error(), "OOM ") || strings.Contains(err.Error(), "OOM command not allowed") {
+		return &redisError{kind: ErrRedisOOM, err: err}
+	}
+	if strings.HasPrefix(err.Error(), "STALEEPOCH ") {
+		return &redisError{kind: ErrStaleEpoch, err: err}
+	}
+	return err
+}
+
+// opError wraps the error of the operation op, e.g. "locker: lock failed: ...", so errors of Redis and ctx
+// and package errors remain matchable with errors.Is and errors.As.
+func opError(op string, err error) error {
+	return fmt.Errorf("locker: %s failed: %w", op, err)
+}
@@ -0,0 +1,46 @@
+package locker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrors(t *testing.T) {
+	require.Equal(t, ErrInvalidResponse, ErrUnexpectedRedisResponse)
+
+	err := fmt.Errorf("wrapped: %w", ErrUnexpectedRedisResponse)
+	require.True(t, errors.Is(err, ErrInvalidResponse))
+
+	err = fmt.Errorf("wrapped: %w", ErrKeyNameClash)
+	require.True(t, errors.Is(err, ErrKeyNameClash))
+	require.False(t, errors.Is(err, ErrInvalidResponse))
+}
+
+func TestLockedError(t *testing.T) {
+	require.NoError(t, ResultAcquired.Err())
+	require.NoError(t, ResultExtended.Err())
+
+	err := Result(42).Err()
+	require.True(t, errors.Is(err, ErrLocked))
+	var lerr *LockedError
+	require.True(t, errors.As(err, &lerr))
+	require.Equal(t, 42*time.Millisecond, lerr.TTL)
+	require.Equal(t, "locker: locked for 42ms", err.Error())
+
+	err = fmt.Errorf("wrapped: %w", err)
+	require.True(t, errors.Is(err, ErrLocked))
+	require.False(t, errors.Is(err, ErrLockLost))
+
+	lr := LockResult{Result: 42}
+	require.True(t, errors.Is(lr.Err(), ErrLocked))
+
+	require.Equal(t, "locker: locked for 0s", Result(0).Err().Error())
+	require.Equal(t, ErrLocked, ResultNoTTL.Err())
+	require.Equal(t, ErrConditionFailed, ResultConditionFailed.Err())
+	require.Equal(t, ErrNotApplied, ResultNotApplied.Err())
+	require.Equal(t, ErrInvalidResponse, Result(-6).Err())
+}
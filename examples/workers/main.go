@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/da440dil/go-locker"
+	"github.com/go-redis/redis/v8"
+)
+
+func main() {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	// Create locker.
+	lkr := locker.NewLocker(client)
+	keys := []string{"job:1", "job:2", "job:3"}
+	err := client.Del(context.Background(), keys...).Err()
+	requireNoError(err)
+
+	// Shut down on interrupt signal, or after a while to finish the example.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(id int, key string) {
+			defer wg.Done()
+			// Apply lock, it is extended in the background until released.
+			wl, err := lkr.NewWorkerLock(ctx, key, 100*time.Millisecond)
+			if err != nil {
+				fmt.Printf("Worker %d failed to apply lock: %v\n", id, err)
+				return
+			}
+			// Release lock on shutdown, even if work panics.
+			defer func() {
+				if err := wl.Release(); err != nil {
+					fmt.Printf("Worker %d lost lock: %v\n", id, err)
+				}
+			}()
+			work(wl.Context())
+		}(i+1, key)
+	}
+	wg.Wait()
+
+	n, err := client.Exists(context.Background(), keys...).Result()
+	requireNoError(err)
+	fmt.Printf("Locks held after shutdown: %d\n", n)
+	// Output:
+	// Locks held after shutdown: 0
+}
+
+// work does some work until ctx is done, i.e. on shutdown or if the lock is lost.
+func work(ctx context.Context) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// some code here
+		}
+	}
+}
+
+func requireNoError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
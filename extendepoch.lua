@@ -0,0 +1,13 @@
+local epoch = tonumber(string.match(ARGV[1], "^(%d+)/")) or 0
+if epoch < (tonumber(redis.call("get", KEYS[2])) or 0) then
+	return redis.error_reply("STALEEPOCH lock epoch is older than the current epoch")
+end
+local token = redis.call("get", KEYS[1])
+if token == ARGV[1] then
+	redis.call("pexpire", KEYS[1], ARGV[2])
+	return -4
+end
+if token == false then
+	return 0
+end
+return redis.call("pttl", KEYS[1])
@@ -0,0 +1,13 @@
+local token = redis.call("get", KEYS[1])
+if token == ARGV[1] then
+	if redis.REDIS_VERSION_NUM and redis.REDIS_VERSION_NUM >= 0x070000 then
+		redis.call("pexpire", KEYS[1], ARGV[2], "gt")
+	else
+		redis.call("pexpire", KEYS[1], ARGV[2])
+	end
+	return -4
+end
+if token == false then
+	return 0
+end
+return redis.call("pttl", KEYS[1])
\ No newline at end of file
@@ -0,0 +1,9 @@
+local token = redis.call("hget", KEYS[1], "token")
+if token == ARGV[1] then
+	redis.call("pexpire", KEYS[1], ARGV[2])
+	return -4
+end
+if token == false then
+	return 0
+end
+return redis.call("pttl", KEYS[1])
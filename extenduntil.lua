@@ -0,0 +1,14 @@
+local t = redis.call("time")
+local ttl = tonumber(ARGV[2]) - (tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000))
+if ttl <= 0 then
+	return 0
+end
+local token = redis.call("get", KEYS[1])
+if token == ARGV[1] then
+	redis.call("pexpire", KEYS[1], ttl)
+	return -4
+end
+if token == false then
+	return 0
+end
+return redis.call("pttl", KEYS[1])
\ No newline at end of file
@@ -0,0 +1,71 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// Gateway is storage interface for applying and releasing locks, so a locker may use a storage other than Redis,
+// e.g. an in-memory one for unit tests of code using locks.
+type Gateway interface {
+	// Lock applies the lock of the key with the value if it is not held by anyone, returning ResultAcquired,
+	// or extends the lock TTL if it is held with the same value, returning ResultExtended,
+	// otherwise returns the TTL of the lock in milliseconds.
+	Lock(ctx context.Context, key, value string, ttl time.Duration) (Result, error)
+	// Unlock releases the lock of the key if it is held with the value, and reports whether it was released.
+	Unlock(ctx context.Context, key, value string) (bool, error)
+}
+
+// redisGateway is Gateway using Redis.
+type redisGateway struct {
+	client RedisClient
+}
+
+// NewRedisGateway creates new gateway using Redis with the scripts returned by LockScript and UnlockScript.
+func NewRedisGateway(client RedisClient) Gateway {
+	return &redisGateway{client: client}
+}
+
+func (g *redisGateway) Lock(ctx context.Context, key, value string, ttl time.Duration) (Result, error) {
+	v, err := lockscr.Run(ctx, g.client, []string{key}, value, int(ttl/time.Millisecond)).Int64()
+	if err != nil {
+		return 0, opError("lock", classify(err))
+	}
+	return Result(v), nil
+}
+
+func (g *redisGateway) Unlock(ctx context.Context, key, value string) (bool, error) {
+	v, err := unlockscr.Run(ctx, g.client, []string{key}, value).Int64()
+	if err != nil {
+		return false, opError("unlock", classify(err))
+	}
+	return v == 1, nil
+}
+
+// NewLockerGateway creates new locker using the gateway. If the gateway is created by NewRedisGateway,
+// the locker is the same as created by NewLocker with the Redis client. Otherwise the locker only applies,
+// extends and releases locks using the gateway, other operations, e.g. Lock.TTL or Locker.Scan,
+// and options which need Redis scripts, e.g. WithStrictRefresh or WithQueueTracking, return ErrUnsupported.
+func NewLockerGateway(gateway Gateway, options ...Option) *Locker {
+	if g, ok := gateway.(*redisGateway); ok {
+		return NewLocker(g.client, options...)
+	}
+	locker := NewLocker(nil, options...)
+	locker.gateway = gateway
+	return locker
+}
+
+// lock applies the lock using the gateway if it is set, otherwise using the lock script.
+func (lock *Lock) lock(ctx context.Context, ttl time.Duration) (Result, error) {
+	g := lock.locker.gateway
+	if g == nil {
+		return lock.apply(ctx, "lock", lock.locker.lockScript(), lock.locker.lockKeys(lock.locker.redisKey(lock.key)), ttl)
+	}
+	start := time.Now()
+	key := lock.locker.redisKey(lock.key)
+	r, err := g.Lock(ctx, key, lock.value, ttl)
+	if err != nil {
+		return r, err
+	}
+	return r, lock.applied(ctx, key, r, ttl, start)
+}
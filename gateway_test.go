@@ -0,0 +1,85 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type GatewayMock struct {
+	mock.Mock
+}
+
+func (m *GatewayMock) Lock(ctx context.Context, key, value string, ttl time.Duration) (Result, error) {
+	arg := m.Called(ctx, key, value, ttl)
+	return arg.Get(0).(Result), arg.Error(1)
+}
+
+func (m *GatewayMock) Unlock(ctx context.Context, key, value string) (bool, error) {
+	arg := m.Called(ctx, key, value)
+	return arg.Bool(0), arg.Error(1)
+}
+
+func TestLockerGateway(t *testing.T) {
+	gatewayMock := &GatewayMock{}
+	locker := NewLockerGateway(gatewayMock)
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	gatewayMock.On("Lock", ctx, key, mock.Anything, ttl).Return(Result(-3), nil).Once()
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+
+	gatewayMock.On("Lock", ctx, key, lr.Token(), ttl).Return(Result(-4), nil).Once()
+	r, err := lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	gatewayMock.On("Lock", ctx, key, lr.Token(), ttl).Return(Result(-1), nil).Once()
+	_, err = lr.Lock.Lock(ctx, ttl)
+	require.Equal(t, ErrKeyNameClash, err)
+
+	_, err = lr.Lock.TTL(ctx)
+	require.Equal(t, ErrUnsupported, err)
+
+	gatewayMock.On("Unlock", ctx, key, lr.Token()).Return(true, nil).Once()
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	gatewayMock.AssertExpectations(t)
+}
+
+func TestRedisGateway(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	gateway := NewRedisGateway(client)
+	r, err := gateway.Lock(ctx, key, "value", ttl)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+	r, err = gateway.Lock(ctx, key, "other", ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	ok, err := gateway.Unlock(ctx, key, "other")
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = gateway.Unlock(ctx, key, "value")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	locker := NewLockerGateway(gateway)
+	require.Equal(t, client, locker.client)
+	require.Nil(t, locker.gateway)
+}
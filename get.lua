@@ -0,0 +1 @@
+return redis.call("get", KEYS[1])
\ No newline at end of file
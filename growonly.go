@@ -0,0 +1,56 @@
+package locker
+
+import (
+	_ "embed"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockgt.lua
+var lockgtsrc string
+var lockgtscr = redis.NewScript(lockgtsrc)
+
+//go:embed extendgt.lua
+var extendgtsrc string
+var extendgtscr = redis.NewScript(extendgtsrc)
+
+// WithGrowOnlyTTL sets locker to extend locks using PEXPIRE with GT flag, so extending a lock
+// never makes its TTL shorter. The flag requires Redis 7, the scripts check the server version
+// on each run and fall back to PEXPIRE without the flag on older versions.
+// It applies to Lock.Lock and Locker.RefreshByToken.
+// Grow-only TTL is opt-in rather than detected at NewLocker: NewLocker makes no Redis calls,
+// extending a lock with a shorter TTL is valid use without the option, and the option
+// can not be combined with WithReentrant.
+func WithGrowOnlyTTL() Option {
+	return func(locker *Locker) {
+		locker.growOnly = true
+	}
+}
+
+// lockScript returns the script applying and extending a lock.
+func (locker *Locker) lockScript() *redis.Script {
+	if locker.reentrant {
+		return lockreentrantscr
+	}
+	if locker.epochKey != "" {
+		return lockepochscr
+	}
+	if locker.growOnly {
+		return lockgtscr
+	}
+	return lockscr
+}
+
+// extendScript returns the script extending a lock.
+func (locker *Locker) extendScript() *redis.Script {
+	if locker.reentrant {
+		return extendreentrantscr
+	}
+	if locker.epochKey != "" {
+		return extendepochscr
+	}
+	if locker.growOnly {
+		return extendgtscr
+	}
+	return extendscr
+}
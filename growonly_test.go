@@ -0,0 +1,72 @@
+package locker
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithGrowOnlyTTL(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	locker := NewLocker(client, WithGrowOnlyTTL(), WithStrictRefresh())
+	lr, err := locker.Lock(ctx, key, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+
+	r, err := lr.Lock.Lock(ctx, time.Second)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+	pttl, err := client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 100*time.Millisecond)
+
+	extended, err := locker.RefreshByToken(ctx, lr.value, []string{key}, 2*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true}, extended)
+	pttl, err = client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > time.Second)
+
+	if info, err := client.Info(ctx, "server").Result(); err == nil && redisVersion7(info) {
+		r, err = lr.Lock.Lock(ctx, 100*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, r.Extended())
+		pttl, err = client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.True(t, pttl > time.Second)
+	}
+
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	clientMock := &ClientMock{}
+	locker = NewLocker(clientMock, WithGrowOnlyTTL())
+	ttl := time.Second
+	clientMock.On("EvalSha", ctx, lockgtscr.Hash(), []string{key}, "token", int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil))
+	_, err = locker.LockWithValue(ctx, key, "token", ttl)
+	require.NoError(t, err)
+	clientMock.AssertExpectations(t)
+}
+
+func redisVersion7(info string) bool {
+	for _, line := range strings.Split(info, "\r\n") {
+		if strings.HasPrefix(line, "redis_version:") {
+			major, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(line, "redis_version:"), ".", 2)[0])
+			return err == nil && major >= 7
+		}
+	}
+	return false
+}
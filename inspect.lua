@@ -0,0 +1,5 @@
+local value = redis.call("get", KEYS[1])
+if value == false then
+	return {-2, false}
+end
+return {redis.call("pttl", KEYS[1]), value}
@@ -0,0 +1,94 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// Lease is a lock renewed in the background, which expires if it is not renewed within a grace period.
+type Lease struct {
+	lock     Lock
+	ttl      time.Duration
+	grace    time.Duration
+	onExpire func()
+	cancel   context.CancelFunc
+	done     chan struct{}
+	err      error
+}
+
+// Lease applies the lock waiting for it to be released as LockWait does, then renews the lock
+// in the background every RefreshInterval(ttl), randomly earlier with WithRefreshJitter,
+// until ctx is done or Release is called.
+// If the lock is lost, or renewing fails for longer than grace since the last renewal,
+// the lease expires: renewing stops and onExpire is called once.
+func (locker *Locker) Lease(ctx context.Context, key string, ttl, grace time.Duration, onExpire func()) (*Lease, error) {
+	lr, err := locker.LockWait(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	lctx, cancel := context.WithCancel(ctx)
+	l := &Lease{
+		lock:     lr.Lock,
+		ttl:      ttl,
+		grace:    grace,
+		onExpire: onExpire,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go l.renew(lctx)
+	return l, nil
+}
+
+// Done returns the channel which is closed when the lease expires or is released.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Release stops renewing the lease and releases the lock, it is safe to call Release more than once.
+// Returns ErrLockLost if the lease expired before being released.
+func (l *Lease) Release() error {
+	l.cancel()
+	<-l.done
+	return l.err
+}
+
+func (l *Lease) renew(ctx context.Context) {
+	defer close(l.done)
+	timer := time.NewTimer(l.lock.locker.refreshInterval(l.ttl))
+	defer timer.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			uctx, cancel := context.WithTimeout(context.Background(), l.ttl)
+			defer cancel()
+			_, l.err = l.lock.Unlock(uctx)
+			return
+		case <-timer.C:
+			timer.Reset(l.lock.locker.refreshInterval(l.ttl))
+			r, err := l.lock.refresh(ctx, l.ttl)
+			if err == nil && r.Extended() {
+				renewed = time.Now()
+				continue
+			}
+			if err == nil || time.Since(renewed) > l.grace {
+				if err == nil && r.Acquired() {
+					uctx, cancel := context.WithTimeout(context.Background(), l.ttl)
+					defer cancel()
+					_, _ = l.lock.Unlock(uctx)
+				}
+				if ctx.Err() != nil {
+					continue
+				}
+				l.err = ErrLockLost
+				if err == nil {
+					l.lock.locker.lost(l.lock.key)
+				}
+				if l.onExpire != nil {
+					l.onExpire()
+				}
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLease(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 200 * time.Millisecond
+	locker := NewLocker(client)
+
+	expired := make(chan struct{}, 1)
+	onExpire := func() { expired <- struct{}{} }
+
+	l, err := locker.Lease(ctx, key, ttl, ttl, onExpire)
+	require.NoError(t, err)
+
+	time.Sleep(2 * ttl) // the lease is renewed in the background
+
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, l.lock.value, v)
+
+	require.NoError(t, l.Release())
+	require.NoError(t, l.Release())
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+	require.Len(t, expired, 0)
+
+	l, err = locker.Lease(ctx, key, ttl, ttl, onExpire)
+	require.NoError(t, err)
+	err = client.Set(ctx, key, "value", ttl).Err()
+	require.NoError(t, err)
+	select {
+	case <-expired:
+	case <-time.After(ttl):
+		t.Fatal("the lease is not expired")
+	}
+	<-l.Done()
+	require.Equal(t, ErrLockLost, l.Release())
+	v, err = client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, "value", v)
+}
+
+func TestLeaseGrace(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock)
+
+	ctx := context.Background()
+	key := "key"
+	ttl := 100 * time.Millisecond
+	grace := 200 * time.Millisecond
+	e := errors.New("redis is unavailable")
+	clientMock.On("EvalSha", mock.Anything, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(-3)), nil)).Once()
+	clientMock.On("EvalSha", mock.Anything, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e))
+	clientMock.On("EvalSha", mock.Anything, unlockscr.Hash(), []string{key}, mock.Anything).Return(redis.NewCmdResult(interface{}(int64(1)), nil))
+
+	expired := make(chan time.Time, 1)
+	start := time.Now()
+	l, err := locker.Lease(ctx, key, ttl, grace, func() { expired <- time.Now() })
+	require.NoError(t, err)
+
+	select {
+	case at := <-expired:
+		require.True(t, at.Sub(start) > grace)
+	case <-time.After(2 * grace):
+		t.Fatal("the lease is not expired")
+	}
+	require.Equal(t, ErrLockLost, l.Release())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -17,12 +18,65 @@ var lockscr = redis.NewScript(locksrc)
 var unlocksrc string
 var unlockscr = redis.NewScript(unlocksrc)
 
-// Result of applying a lock.
+//go:embed get.lua
+var getsrc string
+var getscr = redis.NewScript(getsrc)
+
+//go:embed unlockttl.lua
+var unlockttlsrc string
+var unlockttlscr = redis.NewScript(unlockttlsrc)
+
+//go:embed ttl.lua
+var ttlsrc string
+var ttlscr = redis.NewScript(ttlsrc)
+
+// LockScript returns the source of Lua script applying and extending a lock.
+// KEYS[1] is the lock key, ARGV[1] is the lock value, ARGV[2] is the TTL in milliseconds.
+func LockScript() string {
+	return locksrc
+}
+
+// UnlockScript returns the source of Lua script releasing a lock.
+// KEYS[1] is the lock key, ARGV[1] is the lock value.
+func UnlockScript() string {
+	return unlocksrc
+}
+
+// Result of applying a lock: one of the result constants if the lock is applied,
+// otherwise the TTL in milliseconds of the lock held by someone else.
 type Result int64
 
-// OK is success flag of applying a lock.
+// Results returned instead of the TTL of a lock, negative as TTL is never negative.
+// Implementations of Gateway return them from Gateway.Lock.
+const (
+	// ResultNoTTL is returned if the key exists and has no TTL, so it is not a lock.
+	ResultNoTTL Result = -1
+	// ResultConditionFailed is returned if the condition of Locker.LockIf failed.
+	ResultConditionFailed Result = -2
+	// ResultAcquired is returned if the lock is applied, as it was not held by anyone.
+	ResultAcquired Result = -3
+	// ResultExtended is returned if the lock TTL is extended, as it is held by the same owner.
+	ResultExtended Result = -4
+	// ResultNotApplied is returned by Locker.LockManyTTL for a key which lock is not applied
+	// because a lock of another key is held by someone else.
+	ResultNotApplied Result = -5
+)
+
+// OK is success flag of applying a lock, which is either acquired or extended.
 func (r Result) OK() bool {
-	return r < -2
+	return r == ResultAcquired || r == ResultExtended
+}
+
+// Acquired is the flag of applying a lock which was not held by anyone.
+// When extending a lock it means the lock had expired before being applied again,
+// so there was a window when anyone could apply the lock.
+func (r Result) Acquired() bool {
+	return r == ResultAcquired
+}
+
+// Extended is the flag of extending TTL of a lock held by the same owner.
+func (r Result) Extended() bool {
+	return r == ResultExtended
 }
 
 // TTL of a lock. Makes sense if operation failed, otherwise ttl is less than 0.
@@ -30,38 +84,338 @@ func (r Result) TTL() time.Duration {
 	return time.Duration(r) * time.Millisecond
 }
 
-// ErrUnexpectedRedisResponse is the error returned when Redis command returns response of unexpected type.
-var ErrUnexpectedRedisResponse = errors.New("locker: unexpected redis response")
+// Err returns nil if the lock is applied or extended, otherwise *LockedError with the TTL of the lock
+// held by someone else, so the result can be checked as an error instead of using OK and TTL.
+// Returns ErrLocked if the lock held by someone else has no TTL, ErrConditionFailed if the condition
+// of Locker.LockIf fails, and ErrNotApplied if the lock is not applied because a lock of another key
+// is held, see Locker.LockManyTTL.
+func (r Result) Err() error {
+	switch {
+	case r.OK():
+		return nil
+	case r >= 0:
+		return &LockedError{TTL: r.TTL()}
+	case r == ResultNoTTL:
+		return ErrLocked
+	case r == ResultConditionFailed:
+		return ErrConditionFailed
+	case r == ResultNotApplied:
+		return ErrNotApplied
+	}
+	return ErrInvalidResponse
+}
+
+// State of a lock after renewing.
+type State int
+
+const (
+	// Lost is the state of a lock held by someone else.
+	Lost State = iota
+	// Renewed is the state of a lock held by the same owner, which TTL is extended.
+	Renewed
+	// Reacquired is the state of a lock which had expired and is applied again.
+	Reacquired
+)
 
 // Lock implements distributed locking.
 type Lock struct {
 	locker *Locker
 	key    string
 	value  string
+	notify bool
+	// adaptive is the flag of a lock applied by Locker.LockAdaptive.
+	adaptive bool
+	state    *lockState
+	// acquiredAt is the time when the lock was applied by the client, zero if the lock was not applied.
+	acquiredAt time.Time
+	// ttl is the TTL the lock was last applied with.
+	ttl time.Duration
+}
+
+// lockState is the state of a lock shared by its copies.
+type lockState struct {
+	applied int32
+	lost    int32
 }
 
 // Lock applies the lock if it is not already applied, otherwise extends the lock TTL.
-func (lock Lock) Lock(ctx context.Context, ttl time.Duration) (Result, error) {
-	res, err := lockscr.Run(ctx, lock.locker.client, []string{lock.key}, lock.value, int(ttl/time.Millisecond)).Result()
+// Returns ErrKeyNameClash if the key holds a value without TTL written by something else than a locker.
+// With WithStrictRefresh, once the lock is applied, Lock only extends the lock TTL.
+func (lock *Lock) Lock(ctx context.Context, ttl time.Duration) (Result, error) {
+	ctx, end := lock.locker.startSpan(ctx, "lock", lock.key, ttl)
+	start := time.Now()
+	r, err := lock.relock(ctx, ttl)
+	lock.locker.observeLock(lock.key, r, ttl, start, err)
+	lock.debugf("lock", int64(r), start, err)
+	end(err == nil && r.OK(), err)
+	return r, err
+}
+
+// relock applies the lock or extends the lock TTL as Lock does.
+func (lock *Lock) relock(ctx context.Context, ttl time.Duration) (Result, error) {
+	if lock.adaptive {
+		return lock.applyAdaptive(ctx)
+	}
+	if lock.locker.strictRefresh && lock.state != nil && atomic.LoadInt32(&lock.state.applied) == 1 {
+		return lock.extend(ctx, ttl)
+	}
+	return lock.lock(ctx, ttl)
+}
+
+// apply runs the script applying the lock, the script arguments are the lock value, the TTL and args,
+// op is the operation name for logging.
+func (lock *Lock) apply(ctx context.Context, op string, script *redis.Script, keys []string, ttl time.Duration, args ...interface{}) (Result, error) {
+	start := time.Now()
+	v, err := lock.locker.run(ctx, op, script, keys, append([]interface{}{lock.value, lock.locker.ms(ttl)}, args...)...)
+	if err != nil {
+		return Result(v), err
+	}
+	r := Result(v)
+	return r, lock.applied(ctx, keys[0], r, ttl, start)
+}
+
+// applied handles the result of applying the lock with the Redis key started at start.
+// Returns ErrKeyNameClash if the key exists and has no TTL, so it is not a lock.
+func (lock *Lock) applied(ctx context.Context, key string, r Result, ttl time.Duration, start time.Time) error {
+	if r == ResultNoTTL {
+		return ErrKeyNameClash
+	}
+	if r.Acquired() && lock.locker.verifyAcquire {
+		if err := lock.verify(ctx, key); err != nil {
+			return err
+		}
+	}
+	if r.Acquired() {
+		lock.acquiredAt = start
+	}
+	if r.OK() {
+		lock.ttl = ttl
+	}
+	if lock.state != nil {
+		if atomic.LoadInt32(&lock.state.applied) == 1 && (!r.OK() || r.Acquired()) {
+			atomic.StoreInt32(&lock.state.lost, 1)
+		}
+		if r.OK() {
+			atomic.StoreInt32(&lock.state.applied, 1)
+		}
+	}
+	lock.locker.track(lock, r, ttl, start)
+	return nil
+}
+
+// verify reads the value of the applied lock back to check it is stored.
+func (lock *Lock) verify(ctx context.Context, key string) error {
+	v, err := lock.locker.eval(ctx, "verify", getscr, []string{key})
+	if errors.Is(err, redis.Nil) {
+		return ErrAcquireVerificationFailed
+	}
 	if err != nil {
-		return Result(0), err
+		return err
 	}
-	v, ok := res.(int64)
-	if !ok {
-		return Result(0), ErrUnexpectedRedisResponse
+	if v != lock.value {
+		return ErrAcquireVerificationFailed
 	}
-	return Result(v), nil
+	return nil
+}
+
+// extend extends the lock TTL if the lock is held by the same owner, never applying the lock.
+func (lock *Lock) extend(ctx context.Context, ttl time.Duration) (Result, error) {
+	return lock.apply(ctx, "extend", lock.locker.extendScript(), lock.locker.lockKeys(lock.locker.redisKey(lock.key)), ttl)
 }
 
-// Unlock releases the lock.
-func (lock Lock) Unlock(ctx context.Context) (bool, error) {
-	res, err := unlockscr.Run(ctx, lock.locker.client, []string{lock.key}, lock.value).Result()
+// Heartbeat extends the lock TTL by the TTL the lock was last applied with, if the lock is held by the same owner,
+// and reports whether it is. Unlike Lock it never applies a lock which is not held.
+// Returns false if the lock was never applied.
+func (lock *Lock) Heartbeat(ctx context.Context) (bool, error) {
+	if lock.ttl <= 0 {
+		return false, nil
+	}
+	r, err := lock.extend(ctx, lock.ttl)
 	if err != nil {
 		return false, err
 	}
-	v, ok := res.(int64)
-	if !ok {
-		return false, ErrUnexpectedRedisResponse
+	return r.Extended(), nil
+}
+
+// RenewOrReacquire extends the lock TTL if the lock is held by the same owner,
+// applies the lock if it is not held by anyone, and reports which of these happened.
+// Reacquired means there was a window when anyone could apply the lock,
+// so the work done under the lock may need to be verified.
+func (lock *Lock) RenewOrReacquire(ctx context.Context, ttl time.Duration) (State, error) {
+	r, err := lock.Lock(ctx, ttl)
+	if err != nil {
+		return Lost, err
+	}
+	if r.Extended() {
+		return Renewed, nil
 	}
+	if r.Acquired() {
+		return Reacquired, nil
+	}
+	return Lost, nil
+}
+
+// acquire applies the lock which is not applied yet, returns the number of waiters
+// if queue tracking is enabled.
+func (lock *Lock) acquire(ctx context.Context, ttl time.Duration) (Result, int, error) {
+	var r Result
+	var waiters int
+	var err error
+	if !lock.locker.breaker.allow(lock.key) {
+		return r, waiters, ErrCircuitOpen
+	}
+	spanCtx, end := lock.locker.startSpan(ctx, "lock", lock.key, ttl)
+	start := time.Now()
+	if lock.locker.queue {
+		r, waiters, err = lock.applyQueued(spanCtx, ttl)
+	} else {
+		r, err = lock.lock(spanCtx, ttl)
+	}
+	lock.locker.observeLock(lock.key, r, ttl, start, err)
+	lock.debugf("acquire", int64(r), start, err)
+	end(err == nil && r.OK(), err)
+	lock.cleanup(ctx, err)
+	if err == nil {
+		lock.locker.breaker.record(lock.key, r)
+	}
+	if err == nil && !r.OK() && lock.locker.onContended != nil {
+		lock.locker.onContended(lock.key, r.TTL())
+	}
+	return r, waiters, err
+}
+
+// cleanup releases the lock which is not applied yet if applying failed because ctx is done:
+// Redis may apply the lock anyway, so cleanup tries to release it using a background context
+// with the timeout set by WithCleanupTimeout.
+func (lock *Lock) cleanup(ctx context.Context, err error) {
+	if err != nil && ctx.Err() != nil && lock.locker.cleanupTimeout > 0 {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), lock.locker.cleanupTimeout)
+		defer cancel()
+		_, _ = lock.Unlock(cleanupCtx)
+	}
+}
+
+// Unlock releases the lock, locks created by Locker.LockWait or with WithBlocking also notify waiters.
+// With WithReentrant it decrements the lock depth releasing the lock when it drops to zero,
+// and reports whether the lock is held by the same owner.
+// With WithUnlockGuard, if the lock was lost at any point, Unlock returns ErrLockLost without releasing it.
+func (lock *Lock) Unlock(ctx context.Context) (bool, error) {
+	if lock.locker.unlockGuard && lock.state != nil && atomic.LoadInt32(&lock.state.lost) == 1 {
+		lock.locker.untrack(lock)
+		return false, ErrLockLost
+	}
+	ctx, end := lock.locker.startSpan(ctx, "unlock", lock.key, 0)
+	start := time.Now()
+	ok, err := lock.unlock(ctx)
+	lock.locker.observer.UnlockAttempt(lock.key, ok, time.Since(start), err)
+	var v int64
+	if ok {
+		v = 1
+	}
+	lock.debugf("unlock", v, start, err)
+	end(ok, err)
+	return ok, err
+}
+
+// unlock releases the lock as Unlock does.
+func (lock *Lock) unlock(ctx context.Context) (bool, error) {
+	key := lock.locker.redisKey(lock.key)
+	if g := lock.locker.gateway; g != nil {
+		ok, err := g.Unlock(ctx, key, lock.value)
+		if err != nil {
+			return false, err
+		}
+		lock.locker.untrack(lock)
+		return ok, nil
+	}
+	script, keys, args := unlockscr, []string{key}, []interface{}{lock.value}
+	if lock.adaptive {
+		script = unlockadaptivescr
+		keys = append(keys, adaptiveKey(key))
+	} else if lock.locker.reentrant {
+		script = unlockreentrantscr
+	} else if lock.notify {
+		script = unlocknotifyscr
+		keys = append(keys, notifyKey(key))
+		args = append(args, int(notifyTTL/time.Millisecond))
+	}
+	v, err := lock.locker.run(ctx, "unlock", script, keys, args...)
+	if err != nil {
+		return false, err
+	}
+	lock.locker.untrack(lock)
 	return v == 1, nil
 }
+
+// debugf logs the result of the operation op on the lock started at start, if WithDebugLog is set.
+func (lock *Lock) debugf(op string, v int64, start time.Time, err error) {
+	if !lock.locker.debugLog || lock.locker.logger == nil {
+		return
+	}
+	d := time.Since(start)
+	if err != nil {
+		lock.locker.logger.Printf("locker: %s of key %q by owner %s failed after %v: %v", op, lock.key, lock.OwnerHash(), d, err)
+		return
+	}
+	lock.locker.logger.Printf("locker: %s of key %q by owner %s returned %d in %v", op, lock.key, lock.OwnerHash(), v, d)
+}
+
+// UnlockTTL releases the lock as Unlock does, and returns the remaining TTL of the lock at release,
+// e.g. to measure how often locks are released well before expiry. If the lock is not held by the same owner,
+// returns false and -2ms, as Lock.TTL does. It does not support WithReentrant and locks applied by Locker.LockAdaptive.
+func (lock *Lock) UnlockTTL(ctx context.Context) (bool, time.Duration, error) {
+	if lock.locker.unlockGuard && lock.state != nil && atomic.LoadInt32(&lock.state.lost) == 1 {
+		lock.locker.untrack(lock)
+		return false, 0, ErrLockLost
+	}
+	key := lock.locker.redisKey(lock.key)
+	keys, args := []string{key}, []interface{}{lock.value}
+	if lock.notify {
+		keys = append(keys, notifyKey(key))
+		args = append(args, int(notifyTTL/time.Millisecond))
+	}
+	v, err := lock.locker.run(ctx, "unlock", unlockttlscr, keys, args...)
+	if err != nil {
+		return false, 0, err
+	}
+	lock.locker.untrack(lock)
+	return v != -2, time.Duration(v) * time.Millisecond, nil
+}
+
+// UnlockHeld releases the lock as Unlock does, and returns the time the lock was held
+// measured by the client since the lock was applied, or 0 if the lock was not applied.
+func (lock *Lock) UnlockHeld(ctx context.Context) (bool, time.Duration, error) {
+	var held time.Duration
+	if !lock.acquiredAt.IsZero() {
+		held = time.Since(lock.acquiredAt)
+	}
+	ok, err := lock.Unlock(ctx)
+	return ok, held, err
+}
+
+// OwnerHash returns the first 8 hex digits of SHA-256 hash of the lock value, which identifies the owner
+// in logs without exposing the value which allows to extend and release the lock. It matches LockInfo.Owner.
+func (lock *Lock) OwnerHash() string {
+	return ownerHash(lock.value)
+}
+
+// Key returns the key of the lock, without the prefix set by WithKeyPrefix.
+func (lock *Lock) Key() string {
+	return lock.key
+}
+
+// Token returns the lock value, which is stored in Redis as is, e.g. to match it with the value of the key.
+// Anyone knowing the value may extend and release the lock, see OwnerHash for logging.
+func (lock *Lock) Token() string {
+	return lock.value
+}
+
+// TTL returns the remaining TTL of the lock without extending it, if the lock is held by the same owner,
+// otherwise returns -2ms, as PTTL does for a key which does not exist.
+func (lock *Lock) TTL(ctx context.Context) (time.Duration, error) {
+	v, err := lock.locker.run(ctx, "ttl", ttlscr, []string{lock.locker.redisKey(lock.key)}, lock.value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(v) * time.Millisecond, nil
+}
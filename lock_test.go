@@ -22,21 +22,27 @@ func TestLock(t *testing.T) {
 	ttl := 500 * time.Millisecond
 	locker := NewLocker(client)
 
-	lock1 := &Lock{locker, key, "token1"}
+	lock1 := &Lock{locker: locker, key: key, value: "token1"}
 	result, err := lock1.Lock(ctx, ttl)
 	require.NoError(t, err)
 	require.True(t, result.OK())
-	require.Equal(t, -3*time.Millisecond, result.TTL())
+	require.True(t, result.Acquired())
+	require.False(t, result.Extended())
+	require.Equal(t, ResultAcquired, result)
 
 	result, err = lock1.Lock(ctx, ttl)
 	require.NoError(t, err)
 	require.True(t, result.OK())
-	require.Equal(t, -4*time.Millisecond, result.TTL())
+	require.False(t, result.Acquired())
+	require.True(t, result.Extended())
+	require.Equal(t, ResultExtended, result)
 
-	lock2 := &Lock{locker, key, "token2"}
+	lock2 := &Lock{locker: locker, key: key, value: "token2"}
 	result, err = lock2.Lock(ctx, ttl)
 	require.NoError(t, err)
 	require.False(t, result.OK())
+	require.False(t, result.Acquired())
+	require.False(t, result.Extended())
 	require.True(t, result.TTL() >= 0 && result.TTL() <= ttl)
 
 	time.Sleep(result.TTL() + 100*time.Millisecond) // wait for the ttl of the key is over
@@ -44,12 +50,19 @@ func TestLock(t *testing.T) {
 	result, err = lock2.Lock(ctx, ttl)
 	require.NoError(t, err)
 	require.True(t, result.OK())
-	require.Equal(t, -3*time.Millisecond, result.TTL())
+	require.Equal(t, ResultAcquired, result)
 
 	ok, err := lock1.Unlock(ctx)
 	require.NoError(t, err)
 	require.False(t, ok)
 
+	time.Sleep(ttl + 100*time.Millisecond) // wait for the ttl of the key is over
+
+	result, err = lock2.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Acquired()) // the lock expired and was applied again
+
 	ok, err = lock2.Unlock(ctx)
 	require.NoError(t, err)
 	require.True(t, ok)
@@ -58,20 +71,20 @@ func TestLock(t *testing.T) {
 	locker.client = clientMock
 
 	token := "token"
-	lock := &Lock{locker, key, token}
+	lock := &Lock{locker: locker, key: key, value: token}
 	keys := []string{key}
 
 	ttlMs := int(ttl / time.Millisecond)
 	e := errors.New("redis error")
 	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, token, ttlMs).Return(redis.NewCmdResult("", e))
 	_, err = lock.Lock(ctx, ttl)
-	require.Equal(t, e, err)
+	require.ErrorIs(t, err, e)
 	clientMock.On("EvalSha", ctx, unlockscr.Hash(), keys, token).Return(redis.NewCmdResult("", e))
 	_, err = lock.Unlock(ctx)
-	require.Equal(t, e, err)
+	require.ErrorIs(t, err, e)
 
 	token = ""
-	lock = &Lock{locker, key, token}
+	lock = &Lock{locker: locker, key: key, value: token}
 	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, token, ttlMs).Return(redis.NewCmdResult("", nil))
 	_, err = lock.Lock(ctx, ttl)
 	require.Equal(t, ErrUnexpectedRedisResponse, err)
@@ -79,5 +92,419 @@ func TestLock(t *testing.T) {
 	_, err = lock.Unlock(ctx)
 	require.Equal(t, ErrUnexpectedRedisResponse, err)
 
+	token = "readonly"
+	lock = &Lock{locker: locker, key: key, value: token}
+	e = errors.New("READONLY You can't write against a read only replica.")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, token, ttlMs).Return(redis.NewCmdResult(nil, e))
+	_, err = lock.Lock(ctx, ttl)
+	require.True(t, errors.Is(err, ErrReadOnly))
+	require.True(t, errors.Is(err, e))
+	clientMock.On("EvalSha", ctx, unlockscr.Hash(), keys, token).Return(redis.NewCmdResult(nil, e))
+	_, err = lock.Unlock(ctx)
+	require.True(t, errors.Is(err, ErrReadOnly))
+
+	token = "oom"
+	lock = &Lock{locker: locker, key: key, value: token}
+	e = errors.New("OOM command not allowed when used memory > 'maxmemory'.")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, token, ttlMs).Return(redis.NewCmdResult(nil, e))
+	_, err = lock.Lock(ctx, ttl)
+	require.True(t, errors.Is(err, ErrRedisOOM))
+	require.True(t, errors.Is(err, e))
+	require.False(t, errors.Is(err, ErrReadOnly))
+
 	clientMock.AssertExpectations(t)
 }
+
+func TestRenewOrReacquire(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client)
+
+	lock1 := &Lock{locker: locker, key: key, value: "token1"}
+	state, err := lock1.RenewOrReacquire(ctx, ttl)
+	require.NoError(t, err)
+	require.Equal(t, Reacquired, state)
+
+	state, err = lock1.RenewOrReacquire(ctx, ttl)
+	require.NoError(t, err)
+	require.Equal(t, Renewed, state)
+
+	lock2 := &Lock{locker: locker, key: key, value: "token2"}
+	state, err = lock2.RenewOrReacquire(ctx, ttl)
+	require.NoError(t, err)
+	require.Equal(t, Lost, state)
+
+	time.Sleep(ttl + 100*time.Millisecond) // wait for the ttl of the key is over
+
+	state, err = lock1.RenewOrReacquire(ctx, ttl)
+	require.NoError(t, err)
+	require.Equal(t, Reacquired, state)
+
+	clientMock := &ClientMock{}
+	locker.client = clientMock
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, "token1", int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e))
+	state, err = lock1.RenewOrReacquire(ctx, ttl)
+	require.ErrorIs(t, err, e)
+	require.Equal(t, Lost, state)
+}
+
+func TestVerifyAcquire(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client, WithVerifyAcquire())
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	clientMock := &ClientMock{}
+	locker = NewLocker(clientMock, WithVerifyAcquire())
+	token := "token"
+	keys := []string{key}
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, token, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(-3)), nil))
+	clientMock.On("EvalSha", ctx, getscr.Hash(), keys).Return(redis.NewCmdResult(nil, redis.Nil)).Once()
+	_, err = locker.LockWithValue(ctx, key, token, ttl)
+	require.Equal(t, ErrAcquireVerificationFailed, err)
+
+	clientMock.On("EvalSha", ctx, getscr.Hash(), keys).Return(redis.NewCmdResult("other", nil)).Once()
+	_, err = locker.LockWithValue(ctx, key, token, ttl)
+	require.Equal(t, ErrAcquireVerificationFailed, err)
+
+	clientMock.On("EvalSha", ctx, getscr.Hash(), keys).Return(redis.NewCmdResult(token, nil)).Once()
+	lr, err = locker.LockWithValue(ctx, key, token, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	clientMock.AssertExpectations(t)
+}
+
+func TestScripts(t *testing.T) {
+	require.Equal(t, lockscr.Hash(), redis.NewScript(LockScript()).Hash())
+	require.Equal(t, unlockscr.Hash(), redis.NewScript(UnlockScript()).Hash())
+}
+
+func TestStrictRefresh(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client, WithStrictRefresh())
+
+	lock, err := locker.NewLock(key)
+	require.NoError(t, err)
+	r, err := lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+
+	r, err = lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	time.Sleep(ttl + 100*time.Millisecond) // wait for the ttl of the key is over
+
+	r, err = lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.Equal(t, time.Duration(0), r.TTL())
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+	r, err = lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	r, err = lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.True(t, r.TTL() > 0 && r.TTL() <= ttl)
+}
+
+func TestUnlockGuard(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client, WithUnlockGuard())
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	r, err := lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	lr, err = locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	time.Sleep(ttl + 100*time.Millisecond) // wait for the ttl of the key is over
+
+	r, err = lr.Lock.Lock(ctx, time.Second)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+	ok, err = lr.Unlock(ctx)
+	require.Equal(t, ErrLockLost, err)
+	require.False(t, ok)
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, lr.value, v)
+
+	err = client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	lr, err = locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	lr2, err := locker.LockWithValue(ctx, key, "token", ttl)
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+	ok, err = lr2.Unlock(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	err = client.Set(ctx, key, "value", ttl).Err()
+	require.NoError(t, err)
+	r, err = lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	ok, err = lr.Unlock(ctx)
+	require.Equal(t, ErrLockLost, err)
+	require.False(t, ok)
+}
+
+func TestUnlockHeld(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	time.Sleep(50 * time.Millisecond)
+
+	r, err := lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	ok, held, err := lr.UnlockHeld(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, held >= 50*time.Millisecond && held < ttl)
+
+	err = client.Set(ctx, key, "value", ttl).Err()
+	require.NoError(t, err)
+	lr, err = locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	ok, held, err = lr.UnlockHeld(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, time.Duration(0), held)
+}
+
+func TestKeyNameClash(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Set(ctx, key, "value", 0).Err()
+	require.NoError(t, err)
+	defer client.Del(ctx, key)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client)
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.Equal(t, ErrKeyNameClash, err)
+	require.False(t, lr.OK())
+
+	_, err = locker.LockWait(ctx, key, ttl)
+	require.Equal(t, ErrKeyNameClash, err)
+
+	locker = NewLocker(client, WithQueueTracking())
+	_, err = locker.Lock(ctx, key, ttl)
+	require.Equal(t, ErrKeyNameClash, err)
+
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, "value", v)
+}
+
+func TestHeartbeat(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	err = client.PExpire(ctx, key, 100*time.Millisecond).Err()
+	require.NoError(t, err)
+	ok, err := lr.Heartbeat(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	pttl, err := client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > ttl/2 && pttl <= ttl)
+
+	err = client.Set(ctx, key, "value", ttl).Err()
+	require.NoError(t, err)
+	ok, err = lr.Heartbeat(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	lr2, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+	ok, err = lr2.Heartbeat(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestOwnerHash(t *testing.T) {
+	lock := &Lock{value: "token"}
+	require.Equal(t, "3c469e9d", lock.OwnerHash())
+	require.Equal(t, lock.OwnerHash(), (&Lock{value: "token"}).OwnerHash())
+	require.NotEqual(t, lock.OwnerHash(), (&Lock{value: "token1"}).OwnerHash())
+}
+
+func TestToken(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	locker := NewLocker(client)
+	lr, err := locker.Lock(ctx, key, time.Second)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, v, lr.Token())
+
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+}
+
+func TestLockTTL(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	d, err := lr.Lock.TTL(ctx)
+	require.NoError(t, err)
+	require.True(t, d > 0 && d <= ttl)
+	d2, err := lr.Lock.TTL(ctx)
+	require.NoError(t, err)
+	require.True(t, d2 <= d)
+
+	lr2, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+	require.True(t, lr2.TTL() > 0)
+	d, err = lr2.Lock.TTL(ctx)
+	require.NoError(t, err)
+	require.Equal(t, -2*time.Millisecond, d)
+
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+	d, err = lr.Lock.TTL(ctx)
+	require.NoError(t, err)
+	require.Equal(t, -2*time.Millisecond, d)
+}
+
+func TestUnlockTTL(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key, notifyKey(key)).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	for _, locker := range []*Locker{NewLocker(client), NewLocker(client, WithBlocking())} {
+		lr, err := locker.Lock(ctx, key, ttl)
+		require.NoError(t, err)
+		require.True(t, lr.OK())
+
+		lr2, err := locker.Lock(ctx, key, ttl)
+		require.NoError(t, err)
+		require.False(t, lr2.OK())
+		ok, d, err := lr2.UnlockTTL(ctx)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Equal(t, -2*time.Millisecond, d)
+
+		ok, d, err = lr.UnlockTTL(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.True(t, d > 0 && d <= ttl)
+		n, err := client.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		require.Equal(t, int64(0), n)
+	}
+	n, err := client.Exists(ctx, notifyKey(key)).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+}
@@ -0,0 +1,18 @@
+local n = tonumber(redis.call("get", KEYS[2])) or 0
+local token = redis.call("get", KEYS[1])
+if token ~= false and token ~= ARGV[1] then
+	return {redis.call("pttl", KEYS[1]), 0}
+end
+if token == false then
+	n = n + 1
+end
+local ttl = math.floor(tonumber(ARGV[2]) * tonumber(ARGV[4]) ^ (n - 1))
+if ttl > tonumber(ARGV[3]) then
+	ttl = tonumber(ARGV[3])
+end
+redis.call("set", KEYS[1], ARGV[1], "px", ttl)
+redis.call("set", KEYS[2], n, "px", ttl + tonumber(ARGV[3]))
+if token == false then
+	return {-3, ttl}
+end
+return {-4, ttl}
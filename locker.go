@@ -4,14 +4,20 @@ package locker
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
-// RedisClient is redis scripter interface.
+// RedisClient is redis scripter interface. It is implemented by *redis.Client, *redis.ClusterClient,
+// *redis.Ring and redis.UniversalClient, see NewLockerUniversal.
 type RedisClient interface {
 	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
 	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd
@@ -19,51 +25,398 @@ type RedisClient interface {
 	ScriptLoad(ctx context.Context, script string) *redis.StringCmd
 }
 
+// Logger is logger interface, *log.Logger implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
 // Locker defines parameters for creating new lock.
 type Locker struct {
-	client RedisClient
-	buf    []byte
-	mu     sync.Mutex
+	tokenErrors    uint64 // first for 64-bit alignment of atomic operations
+	epoch          int64
+	noScript       uint64
+	reloadMode     int32
+	client         RedisClient
+	buf            []byte
+	mu             sync.Mutex
+	token          func() (string, error)
+	cleanupTimeout time.Duration
+	maxValueLength int
+	logger         Logger
+	slowThreshold  time.Duration
+	escapeKeys     bool
+	tracker        *tracker
+	verifyAcquire  bool
+	strictRefresh  bool
+	queue          bool
+	growOnly       bool
+	onContended    func(key string, ttl time.Duration)
+	unlockGuard    bool
+	opTimeout      time.Duration
+	retryable      func(err error) bool
+	retryMaxTTL    time.Duration
+	keyTTLs        map[string]time.Duration
+	keyPatterns    []string
+	defaultTTL     time.Duration
+	fault          func(op string) error
+	refreshJitter  float64
+	owner          func(ctx context.Context) string
+	adaptive       *adaptiveTTL
+	keyMaxLength   int
+	onLost         func(key string)
+	epochKey       string
+	blocking       bool
+	breaker        *breaker
+	nonce          string
+	argFromCtx     func(ctx context.Context) interface{}
+	rounding       TTLRounding
+	flights        *flightGroup
+	retryDelay     time.Duration
+	retryJitter    time.Duration
+	retryCount     int
+	retryLimited   bool
+	gateway        Gateway
+	reentrant      bool
+	keyPrefix      string
+	rand           io.Reader
+	observer       Observer
+	span           SpanFunc
+	debugLog       bool
 }
 
-// NewLocker creates new locker.
-func NewLocker(client RedisClient) *Locker {
-	return &Locker{
-		client: client,
-		buf:    make([]byte, 16),
+// NewLocker creates new locker. It does not connect to Redis, so Redis being unreachable is noticed
+// on the first operation, use NewLockerContext to notice it at startup.
+func NewLocker(client RedisClient, options ...Option) *Locker {
+	locker := &Locker{
+		client:         client,
+		buf:            make([]byte, defaultTokenLength),
+		cleanupTimeout: defaultCleanupTimeout,
+		retryable:      isTransient,
+		retryDelay:     defaultRetryDelay,
+		retryJitter:    defaultRetryJitter,
+		observer:       noopObserver{},
+	}
+	locker.token = locker.randomString
+	for _, option := range options {
+		option(locker)
 	}
+	return locker
 }
 
-// Lock creates and applies new lock.
+// NewLockerUniversal creates new locker as NewLocker does using the client which may be a single node,
+// Redis Cluster, Sentinel or Ring client. A lock uses a single key, so it works with Redis Cluster as is,
+// but scripts of some options also use keys derived from the lock key by adding a suffix,
+// e.g. WithBlocking, WithQueueTracking and Locker.LockAdaptive, so with Redis Cluster lock keys must
+// have hash tags, e.g. "{lock}:foo", for the derived keys to hash to the same slot.
+func NewLockerUniversal(client redis.UniversalClient, options ...Option) *Locker {
+	return NewLocker(client, options...)
+}
+
+// validate checks options which NewLocker does not check.
+func (locker *Locker) validate() error {
+	if locker.retryCount < 0 || locker.retryDelay < 0 || locker.retryJitter < 0 || len(locker.buf) == 0 ||
+		!locker.queueSupported() || !locker.reentrantSupported() {
+		return ErrInvalidOption
+	}
+	return nil
+}
+
+// pingClient is redis client interface for checking connectivity.
+type pingClient interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+// NewLockerContext creates new locker as NewLocker does, but validates options returning ErrInvalidOption,
+// and eagerly connects to Redis: pings it if the client implements Ping, and loads scripts with Preload, so startup fails fast if Redis is unreachable, similar to sql.Open followed by Ping.
+func NewLockerContext(ctx context.Context, client RedisClient, options ...Option) (*Locker, error) {
+	locker := NewLocker(client, options...)
+	if err := locker.validate(); err != nil {
+		return nil, err
+	}
+	if c, ok := client.(pingClient); ok {
+		if err := c.Ping(ctx).Err(); err != nil {
+			return nil, classify(err)
+		}
+	}
+	if err := locker.Preload(ctx); err != nil {
+		return nil, err
+	}
+	return locker, nil
+}
+
+// Preload loads scripts of applying, extending and releasing locks into the Redis script cache with SCRIPT LOAD,
+// so the first operations send only SHA1 digests of the scripts with EVALSHA instead of the scripts.
+// Operations always try EVALSHA first and fall back only if a script is not in the cache,
+// so Preload is not required, but saves the bandwidth of sending scripts, e.g. after Redis restarts.
+// Returns ErrInvalidResponse if Redis replies with a digest which does not match the script.
+func (locker *Locker) Preload(ctx context.Context) error {
+	scripts := []*redis.Script{locker.lockScript(), locker.extendScript(), unlockscr, unlocknotifyscr}
+	if locker.reentrant {
+		scripts = append(scripts, unlockreentrantscr)
+	}
+	for _, script := range scripts {
+		sha, err := script.Load(ctx, locker.client).Result()
+		if err != nil {
+			return opError("preload", classify(err))
+		}
+		if sha != script.Hash() {
+			return ErrInvalidResponse
+		}
+	}
+	return nil
+}
+
+// Lock creates and applies new lock. With WithSingleflight concurrent calls for the same key share a Redis call.
 func (locker *Locker) Lock(ctx context.Context, key string, ttl time.Duration) (LockResult, error) {
+	lock := func() (LockResult, error) {
+		value, err := locker.newValue(ctx, key)
+		if err != nil {
+			return LockResult{}, err
+		}
+		return locker.lockWithValue(ctx, key, value, ttl)
+	}
+	if locker.flights != nil {
+		return locker.lockShared(ctx, key, ttl, lock)
+	}
+	return lock()
+}
+
+// LockWithValue creates and applies new lock using the value instead of a generated one.
+// The value must be unique among lock owners, it is intended mostly for tests.
+// Returns ErrInvalidValue if the value is empty, starts with a NUL byte, which marks values of locks
+// applied by Locker.LockWithPriority, or is longer than set by WithMaxValueLength.
+func (locker *Locker) LockWithValue(ctx context.Context, key string, value string, ttl time.Duration) (LockResult, error) {
+	if value == "" || strings.HasPrefix(value, prioMark) || (locker.maxValueLength > 0 && len(value) > locker.maxValueLength) {
+		return LockResult{}, ErrInvalidValue
+	}
+	return locker.lockWithValue(ctx, key, value, ttl)
+}
+
+// lockWithValue creates and applies new lock using the value without validating it.
+func (locker *Locker) lockWithValue(ctx context.Context, key string, value string, ttl time.Duration) (LockResult, error) {
 	r := LockResult{}
-	value, err := locker.randomString()
+	r.Lock = locker.newLock(key, value)
+	var err error
+	r.Result, r.Waiters, err = r.Lock.acquire(ctx, ttl)
+	return r, err
+}
+
+// newLock creates new lock.
+func (locker *Locker) newLock(key string, value string) Lock {
+	return Lock{locker: locker, key: key, value: value, notify: locker.blocking, state: &lockState{}}
+}
+
+// NewLock creates new lock without applying it. The lock value is created once,
+// so the lock may be applied, extended and released repeatedly with the same value,
+// e.g. to keep stable ownership of a key for a worker lifetime.
+func (locker *Locker) NewLock(key string) (*Lock, error) {
+	value, err := locker.newValue(context.Background(), key)
 	if err != nil {
-		return r, err
+		return nil, err
 	}
-	r.Lock = Lock{
-		locker: locker,
-		key:    key,
-		value:  value,
+	lock := locker.newLock(key, value)
+	return &lock, nil
+}
+
+// LockFromToken creates the lock of the key with the token, which is the value of a lock applied before,
+// e.g. by a process which crashed and persisted Lock.Token, so the lock can be extended or released.
+// The caller is responsible for the token being the value actually stored with the key,
+// otherwise extending the lock fails as the lock is held by someone else, and Unlock releases nothing.
+func (locker *Locker) LockFromToken(key string, token string) *Lock {
+	lock := locker.newLock(key, token)
+	return &lock
+}
+
+// keyEscaper escapes separators in keys.
+var keyEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// hashedKeyPrefixLength is the length of the key prefix kept in a hashed key.
+const hashedKeyPrefixLength = 16
+
+// redisKey creates Redis key from the key, prefixed with the prefix set by WithKeyPrefix.
+func (locker *Locker) redisKey(key string) string {
+	if locker.escapeKeys {
+		key = keyEscaper.Replace(key)
 	}
-	r.Result, err = r.Lock.Lock(ctx, ttl)
-	return r, err
+	if locker.keyMaxLength > 0 && len(key) > locker.keyMaxLength {
+		sum := sha256.Sum256([]byte(key))
+		prefix := key
+		if len(prefix) > hashedKeyPrefixLength {
+			prefix = prefix[:hashedKeyPrefixLength]
+		}
+		key = prefix + "#" + hex.EncodeToString(sum[:16])
+	}
+	return locker.keyPrefix + key
 }
 
-// randomString creates random string to use as lock key value
+// run runs the script which returns integer reply, op is the operation name for logging.
+func (locker *Locker) run(ctx context.Context, op string, script *redis.Script, keys []string, args ...interface{}) (int64, error) {
+	res, err := locker.eval(ctx, op, script, keys, args...)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := res.(int64)
+	if !ok {
+		return 0, ErrInvalidResponse
+	}
+	return v, nil
+}
+
+// eval runs the script, op is the operation name for logging and errors.
+// Scripts applying, extending and releasing locks get the argument set by WithArgFromContext as the last one.
+func (locker *Locker) eval(ctx context.Context, op string, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	if locker.client == nil {
+		return nil, ErrUnsupported
+	}
+	if locker.fault != nil {
+		if err := locker.fault(op); err == ErrLockLost {
+			return int64(0), nil
+		} else if err != nil {
+			return nil, opError(op, err)
+		}
+	}
+	if (op == "lock" || op == "extend") && !locker.reentrantSupported() {
+		return nil, ErrInvalidOption
+	}
+	if locker.argFromCtx != nil && (op == "lock" || op == "extend" || op == "unlock") {
+		args = append(args[:len(args):len(args)], locker.argFromCtx(ctx))
+	}
+	opCtx := ctx
+	if locker.opTimeout > 0 {
+		var cancel context.CancelFunc
+		opCtx, cancel = context.WithTimeout(ctx, locker.opTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	res, err := locker.runScript(opCtx, script, keys, args...)
+	if locker.logger != nil && locker.slowThreshold > 0 && len(keys) > 0 {
+		if d := time.Since(start); d > locker.slowThreshold {
+			locker.logger.Printf("locker: slow %s of key %q took %v", op, keys[0], d)
+		}
+	}
+	if err != nil {
+		if opCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			return nil, opError(op, &redisError{kind: ErrOperationTimeout, err: err})
+		}
+		return nil, opError(op, classify(err))
+	}
+	return res, nil
+}
+
+// Stats contains counters of locker events.
+type Stats struct {
+	// TokenErrors is the number of failures of creating lock values,
+	// e.g. because of broken random source.
+	TokenErrors uint64
+	// NoScriptFallbacks is the number of times a script was not in the Redis script cache,
+	// so it was loaded again.
+	NoScriptFallbacks uint64
+}
+
+// Stats returns counters of locker events.
+func (locker *Locker) Stats() Stats {
+	return Stats{
+		TokenErrors:       atomic.LoadUint64(&locker.tokenErrors),
+		NoScriptFallbacks: atomic.LoadUint64(&locker.noScript),
+	}
+}
+
+// runScript runs the script using EVALSHA, if the script is not in the Redis script cache,
+// e.g. because Redis restarted or the cache was flushed, loads the script and retries once.
+func (locker *Locker) runScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	res, err := script.EvalSha(ctx, locker.client, keys, args...).Result()
+	if err != nil && isNoScript(err) {
+		locker.noScriptFallback()
+		if err = script.Load(ctx, locker.client).Err(); err != nil {
+			return nil, err
+		}
+		return script.EvalSha(ctx, locker.client, keys, args...).Result()
+	}
+	if err == nil || err == redis.Nil {
+		atomic.StoreInt32(&locker.reloadMode, 0)
+	}
+	return res, err
+}
+
+// noScriptFallback counts falling back from EVALSHA because the script is not in the Redis script cache.
+func (locker *Locker) noScriptFallback() {
+	atomic.AddUint64(&locker.noScript, 1)
+	atomic.StoreInt32(&locker.reloadMode, 1)
+}
+
+// isNoScript reports whether the error is returned because the script is not in the Redis script cache.
+func isNoScript(err error) bool {
+	return strings.HasPrefix(err.Error(), "NOSCRIPT ")
+}
+
+// ScriptMode returns the mode of running scripts by the last operation: "evalsha" if the script was run
+// from the Redis script cache, or "reload" if it was not in the cache, e.g. because the cache was flushed,
+// so the script was loaded again with SCRIPT LOAD before running it with EVALSHA. Scripts are never sent
+// with EVAL. See also Stats.NoScriptFallbacks.
+func (locker *Locker) ScriptMode() string {
+	if atomic.LoadInt32(&locker.reloadMode) == 1 {
+		return "reload"
+	}
+	return "evalsha"
+}
+
+// newValue creates new lock value, prefixed with the instance nonce if WithInstanceNonce is set,
+// with the owner from ctx if WithOwnerFromContext is set, and with the epoch if WithEpochKey is set.
+// Failures are reported to the observer as failed attempts of applying the locks of the keys.
+func (locker *Locker) newValue(ctx context.Context, keys ...string) (string, error) {
+	value, err := locker.token()
+	if err != nil {
+		atomic.AddUint64(&locker.tokenErrors, 1)
+		for _, key := range keys {
+			locker.observer.LockAttempt(key, false, 0, 0, err)
+		}
+		return value, err
+	}
+	if locker.nonce != "" {
+		value = locker.nonce + "." + value
+	}
+	if locker.owner != nil {
+		if owner := locker.owner(ctx); owner != "" {
+			value = owner + "#" + value
+		}
+	}
+	return locker.epochValue(value), nil
+}
+
+// randomString creates random string to use as lock key value, without padding to keep values compact
 func (locker *Locker) randomString() (string, error) {
 	locker.mu.Lock()
 	defer locker.mu.Unlock()
 
-	_, err := rand.Reader.Read(locker.buf)
-	if err != nil {
+	if len(locker.buf) == 0 {
+		return "", ErrInvalidOption
+	}
+	if err := locker.readRandom(locker.buf); err != nil {
 		return "", err
 	}
-	return base64.URLEncoding.EncodeToString(locker.buf), nil
+	return base64.RawURLEncoding.EncodeToString(locker.buf), nil
+}
+
+// readRandom fills b from the reader set by WithRandReader, or crypto/rand by default, locker.mu must be held.
+func (locker *Locker) readRandom(b []byte) error {
+	r := locker.rand
+	if r == nil {
+		r = rand.Reader
+	}
+	_, err := io.ReadFull(r, b)
+	return err
 }
 
 // LockResult contains new lock and result of applying a lock.
 type LockResult struct {
 	Lock
 	Result
+	// Waiters is the number of owners waiting for the lock including this one,
+	// if the lock is held by someone else and queue tracking is enabled with WithQueueTracking.
+	Waiters int
+}
+
+// TTL of the lock held by someone else, see Result.TTL. Use Lock.TTL to read the remaining TTL of the lock.
+func (r LockResult) TTL() time.Duration {
+	return r.Result.TTL()
 }
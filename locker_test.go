@@ -3,7 +3,10 @@ package locker
 import (
 	"context"
 	"crypto/rand"
+	"errors"
+	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -31,23 +34,26 @@ func (m *ClientMock) ScriptExists(ctx context.Context, hashes ...string) *redis.
 }
 
 func (m *ClientMock) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
-	return nil
+	arg := m.Called(ctx, script)
+	return arg.Get(0).(*redis.StringCmd)
 }
 
-func TestLocker(t *testing.T) {
-	randReader := rand.Reader
-	rand.Reader = strings.NewReader("qwertyqwertyqwer")
-	defer func() {
-		rand.Reader = randReader
-	}()
+type LoggerMock struct {
+	lines []string
+}
+
+func (m *LoggerMock) Printf(format string, v ...interface{}) {
+	m.lines = append(m.lines, fmt.Sprintf(format, v...))
+}
 
+func TestLocker(t *testing.T) {
 	clientMock := &ClientMock{}
-	locker := NewLocker(clientMock)
+	locker := NewLocker(clientMock, WithRandReader(strings.NewReader("qwertyqwertyqwer")))
 
 	ctx := context.Background()
 	key := "key"
 	ttl := 500 * time.Millisecond
-	value := "cXdlcnR5cXdlcnR5cXdlcg=="
+	value := "cXdlcnR5cXdlcnR5cXdlcg"
 	keys := []string{key}
 	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(-3)), nil))
 
@@ -56,7 +62,831 @@ func TestLocker(t *testing.T) {
 	require.Equal(t, value, r.value)
 
 	clientMock.AssertExpectations(t)
+	require.Equal(t, uint64(0), locker.Stats().TokenErrors)
 
 	_, err = locker.Lock(ctx, key, ttl)
 	require.Equal(t, io.EOF, err)
+	require.Equal(t, uint64(1), locker.Stats().TokenErrors)
+
+	_, err = locker.NewLock(key)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, uint64(2), locker.Stats().TokenErrors)
+}
+
+func TestWithCounterToken(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock, WithCounterToken("worker"))
+
+	ctx := context.Background()
+	key := "key"
+	ttl := 500 * time.Millisecond
+	keys := []string{key}
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(-3)), nil))
+
+	r1, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	r2, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+
+	prefix := "worker-" + processStart + "-"
+	require.True(t, strings.HasPrefix(r1.value, prefix))
+	require.True(t, strings.HasPrefix(r2.value, prefix))
+	n1, err := strconv.ParseUint(strings.TrimPrefix(r1.value, prefix), 10, 64)
+	require.NoError(t, err)
+	n2, err := strconv.ParseUint(strings.TrimPrefix(r2.value, prefix), 10, 64)
+	require.NoError(t, err)
+	require.Equal(t, n1+1, n2)
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestLockWithValue(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	locker := NewLocker(client)
+	ttl := 500 * time.Millisecond
+	value := "value"
+
+	lr, err := locker.LockWithValue(ctx, key, value, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	require.Equal(t, value, lr.value)
+
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, value, v)
+
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = locker.LockWithValue(ctx, key, "", ttl)
+	require.Equal(t, ErrInvalidValue, err)
+
+	locker = NewLocker(client, WithMaxValueLength(len(value)))
+	lr, err = locker.LockWithValue(ctx, key, value, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	_, err = locker.LockWithValue(ctx, key, value+"1", ttl)
+	require.Equal(t, ErrInvalidValue, err)
+	ok, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	locker = NewLocker(client, WithMaxValueLength(1), WithOwnerFromContext(func(ctx context.Context) string { return "owner" }))
+	lr, err = locker.Lock(ctx, key, ttl) // the generated value is not limited
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	ok, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestLockerCleanup(t *testing.T) {
+	key := "key"
+	value := "value"
+	ttl := 500 * time.Millisecond
+	keys := []string{key}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock)
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, context.Canceled))
+	clientMock.On("EvalSha", mock.Anything, unlockscr.Hash(), keys, value).Return(redis.NewCmdResult(interface{}(int64(1)), nil))
+
+	_, err := locker.LockWithValue(ctx, key, value, ttl)
+	require.ErrorIs(t, err, context.Canceled)
+	clientMock.AssertExpectations(t)
+
+	clientMock = &ClientMock{}
+	locker = NewLocker(clientMock, WithCleanupTimeout(0))
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, context.Canceled))
+
+	_, err = locker.LockWithValue(ctx, key, value, ttl)
+	require.ErrorIs(t, err, context.Canceled)
+	clientMock.AssertExpectations(t)
+	clientMock.AssertNotCalled(t, "EvalSha", mock.Anything, unlockscr.Hash(), keys, value)
+}
+
+func TestNewLock(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	locker := NewLocker(client)
+	ttl := 500 * time.Millisecond
+
+	lock, err := locker.NewLock(key)
+	require.NoError(t, err)
+	value := lock.value
+
+	for i := 0; i < 2; i++ {
+		r, err := lock.Lock(ctx, ttl)
+		require.NoError(t, err)
+		require.True(t, r.OK())
+		require.Equal(t, value, lock.value)
+
+		ok, err := lock.Unlock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	randReader := rand.Reader
+	rand.Reader = strings.NewReader("")
+	defer func() {
+		rand.Reader = randReader
+	}()
+	_, err = locker.NewLock(key)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestWithSlowThreshold(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	logger := &LoggerMock{}
+	locker := NewLocker(client, WithLogger(logger), WithSlowThreshold(time.Nanosecond))
+	lr, err := locker.Lock(ctx, key, time.Second)
+	require.NoError(t, err)
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.Len(t, logger.lines, 2)
+	require.True(t, strings.HasPrefix(logger.lines[0], `locker: slow lock of key "key" took `))
+	require.True(t, strings.HasPrefix(logger.lines[1], `locker: slow unlock of key "key" took `))
+
+	logger = &LoggerMock{}
+	locker = NewLocker(client, WithLogger(logger), WithSlowThreshold(time.Hour))
+	lr, err = locker.Lock(ctx, key, time.Second)
+	require.NoError(t, err)
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.Len(t, logger.lines, 0)
+}
+
+func TestWithKeyEscaping(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.Del(ctx, "a%3Ab", "a%253Ab", "a", "a:notify", "a%3Anotify").Err()
+	require.NoError(t, err)
+
+	locker := NewLocker(client, WithKeyEscaping())
+	ttl := time.Second
+
+	lr1, err := locker.Lock(ctx, "a:b", ttl)
+	require.NoError(t, err)
+	require.True(t, lr1.OK())
+	lr2, err := locker.Lock(ctx, "a%3Ab", ttl)
+	require.NoError(t, err)
+	require.True(t, lr2.OK())
+
+	v, err := client.Get(ctx, "a%3Ab").Result()
+	require.NoError(t, err)
+	require.Equal(t, lr1.value, v)
+	v, err = client.Get(ctx, "a%253Ab").Result()
+	require.NoError(t, err)
+	require.Equal(t, lr2.value, v)
+
+	lr3, err := locker.LockWait(ctx, "a", ttl)
+	require.NoError(t, err)
+	require.True(t, lr3.OK())
+	lr4, err := locker.Lock(ctx, "a:notify", ttl)
+	require.NoError(t, err)
+	require.True(t, lr4.OK())
+
+	for _, lr := range []LockResult{lr1, lr2, lr3, lr4} {
+		ok, err := lr.Unlock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	n, err := client.Exists(ctx, "a:notify").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+}
+
+func TestWithOnContended(t *testing.T) {
+	clientMock := &ClientMock{}
+	var keys []string
+	var ttls []time.Duration
+	locker := NewLocker(clientMock, WithOnContended(func(key string, ttl time.Duration) {
+		keys = append(keys, key)
+		ttls = append(ttls, ttl)
+	}))
+
+	ctx := context.Background()
+	key := "key"
+	ttl := 500 * time.Millisecond
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, "token1", int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil))
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, "token2", int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(42), nil))
+
+	lr, err := locker.LockWithValue(ctx, key, "token1", ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	require.Len(t, keys, 0)
+
+	lr, err = locker.LockWithValue(ctx, key, "token2", ttl)
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	require.Equal(t, []string{key}, keys)
+	require.Equal(t, []time.Duration{42 * time.Millisecond}, ttls)
+}
+
+func TestWithOperationTimeout(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock, WithOperationTimeout(10*time.Millisecond), WithCleanupTimeout(0))
+
+	key := "key"
+	ttl := 500 * time.Millisecond
+	clientMock.On("EvalSha", mock.Anything, lockscr.Hash(), []string{key}, "token", int(ttl/time.Millisecond)).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(redis.NewCmdResult(nil, context.DeadlineExceeded))
+
+	_, err := locker.LockWithValue(context.Background(), key, "token", ttl)
+	require.True(t, errors.Is(err, ErrOperationTimeout))
+	require.NotEqual(t, context.DeadlineExceeded, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = locker.LockWithValue(ctx, key, "token", ttl)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWithFaultInjector(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	e := errors.New("injected error")
+	var fault error
+	var ops []string
+	ttl := time.Second
+	locker := NewLocker(client, WithFaultInjector(func(op string) error {
+		ops = append(ops, op)
+		return fault
+	}))
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	fault = e
+	_, err = lr.Lock.extend(ctx, ttl)
+	require.ErrorIs(t, err, e)
+
+	fault = ErrLockLost
+	r, err := lr.Lock.extend(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, lr.value, v)
+
+	fault = nil
+	ok, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"lock", "extend", "extend", "unlock", "unlock"}, ops)
+}
+
+func TestNewLockerContext(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.ScriptFlush(ctx).Err()
+	require.NoError(t, err)
+
+	locker, err := NewLockerContext(ctx, client)
+	require.NoError(t, err)
+	require.NotNil(t, locker)
+	exists, err := client.ScriptExists(ctx, lockscr.Hash(), extendscr.Hash(), unlockscr.Hash(), unlocknotifyscr.Hash()).Result()
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, true, true, true}, exists)
+
+	unreachable := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer unreachable.Close()
+	locker, err = NewLockerContext(ctx, unreachable)
+	require.Error(t, err)
+	require.Nil(t, locker)
+}
+
+func TestWithOwnerFromContext(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	type ctxKey struct{}
+	ttl := time.Second
+	locker := NewLocker(client, WithOwnerFromContext(func(ctx context.Context) string {
+		owner, _ := ctx.Value(ctxKey{}).(string)
+		return owner
+	}))
+
+	lr, err := locker.Lock(context.WithValue(ctx, ctxKey{}, "request1"), key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	require.True(t, strings.HasPrefix(lr.value, "request1#"))
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, lr.value, v)
+
+	r, err := lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	lr, err = locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	require.NotContains(t, lr.value, "#")
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+}
+
+func TestWithKeyMaxLength(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := strings.Repeat("k", 100)
+	locker := NewLocker(client, WithKeyMaxLength(64))
+	hashed := locker.redisKey(key)
+	require.Len(t, hashed, 49)
+	require.True(t, strings.HasPrefix(hashed, strings.Repeat("k", 16)+"#"))
+	require.Equal(t, hashed, locker.redisKey(key))
+	require.NotEqual(t, hashed, locker.redisKey(key+"k"))
+	require.Equal(t, "short", locker.redisKey("short"))
+
+	err := client.Del(ctx, hashed).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	v, err := client.Get(ctx, hashed).Result()
+	require.NoError(t, err)
+	require.Equal(t, lr.value, v)
+
+	err = client.PExpire(ctx, hashed, 100*time.Millisecond).Err()
+	require.NoError(t, err)
+	r, err := lr.Lock.extend(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+	pttl, err := client.PTTL(ctx, hashed).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > ttl/2)
+
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	err = client.Get(ctx, hashed).Err()
+	require.Equal(t, redis.Nil, err)
+}
+
+func TestWithInstanceNonce(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker1 := NewLocker(clientMock, WithCounterToken("worker"), WithInstanceNonce())
+	locker2 := NewLocker(clientMock, WithCounterToken("worker"), WithInstanceNonce())
+	require.NotEqual(t, locker1.nonce, locker2.nonce)
+
+	ctx := context.Background()
+	key := "key"
+	ttl := 500 * time.Millisecond
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(-3)), nil))
+
+	r1, err := locker1.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	r2, err := locker2.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(r1.value, locker1.nonce+".worker-"))
+	require.True(t, strings.HasPrefix(r2.value, locker2.nonce+".worker-"))
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestWithArgFromContext(t *testing.T) {
+	clientMock := &ClientMock{}
+	type ctxKey struct{}
+	locker := NewLocker(clientMock, WithArgFromContext(func(ctx context.Context) interface{} {
+		return ctx.Value(ctxKey{})
+	}))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "tenant1")
+	key := "key"
+	ttl := 500 * time.Millisecond
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, "token", int(ttl/time.Millisecond), "tenant1").Return(redis.NewCmdResult(int64(-3), nil))
+	clientMock.On("EvalSha", ctx, unlockscr.Hash(), []string{key}, "token", "tenant1").Return(redis.NewCmdResult(int64(1), nil))
+
+	lr, err := locker.LockWithValue(ctx, key, "token", ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+	err = client.Del(ctx, key).Err()
+	require.NoError(t, err)
+	locker = NewLocker(client, WithArgFromContext(func(ctx context.Context) interface{} {
+		return ctx.Value(ctxKey{})
+	}))
+	lr, err = locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	extended, err := locker.RefreshByToken(ctx, lr.value, []string{key}, ttl)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true}, extended)
+	ok, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestScriptMode(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+	require.Equal(t, "evalsha", locker.ScriptMode())
+
+	err = client.ScriptFlush(ctx).Err()
+	require.NoError(t, err)
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	require.Equal(t, "reload", locker.ScriptMode())
+	require.Equal(t, uint64(1), locker.Stats().NoScriptFallbacks)
+
+	r, err := lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+	require.Equal(t, "evalsha", locker.ScriptMode())
+	require.Equal(t, uint64(1), locker.Stats().NoScriptFallbacks)
+
+	err = client.ScriptFlush(ctx).Err()
+	require.NoError(t, err)
+	extended, err := locker.RefreshByToken(ctx, lr.value, []string{key}, ttl)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true}, extended)
+	require.Equal(t, uint64(2), locker.Stats().NoScriptFallbacks)
+
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+}
+
+func TestLockFromToken(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	lock := NewLocker(client).LockFromToken(key, "token")
+	r, err := lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	ok, err := lock.Unlock(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	lock = NewLocker(client).LockFromToken(key, lr.Token())
+	r, err = lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+	ok, err = lock.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+var (
+	_ RedisClient = (*redis.Client)(nil)
+	_ RedisClient = (*redis.ClusterClient)(nil)
+	_ RedisClient = (*redis.Ring)(nil)
+	_ RedisClient = redis.UniversalClient(nil)
+)
+
+func TestNewLockerUniversal(t *testing.T) {
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"127.0.0.1:6379"}})
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.ClusterSlots(ctx).Err(); err != nil {
+		t.Skip("redis cluster is not available: ", err)
+	}
+	key := "{lock}:foo"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	for _, locker := range []*Locker{NewLockerUniversal(client), NewLockerUniversal(client, WithBlocking())} {
+		lr, err := locker.Lock(ctx, key, time.Second)
+		require.NoError(t, err)
+		require.True(t, lr.OK())
+
+		r, err := locker.Lock(ctx, key, time.Second)
+		require.NoError(t, err)
+		require.False(t, r.OK())
+
+		ok, err := lr.Unlock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
+
+// slotClient is cluster client which fails scripts with keys of different slots, as Redis Cluster does,
+// so hash tags are checked with a single Redis node serving all slots.
+type slotClient struct {
+	*redis.ClusterClient
+}
+
+func (c slotClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	for _, key := range keys[1:] {
+		if keySlot(key) != keySlot(keys[0]) {
+			return redis.NewCmdResult(nil, errors.New("CROSSSLOT Keys in request don't hash to the same slot"))
+		}
+	}
+	return c.ClusterClient.EvalSha(ctx, sha1, keys, args...)
+}
+
+// keySlot returns Redis Cluster slot of the key: CRC16 of the hash tag, or the key without one, modulo 16384.
+func keySlot(key string) int {
+	if s := strings.IndexByte(key, '{'); s >= 0 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+func TestNewLockerUniversalHashTags(t *testing.T) {
+	require.Equal(t, 12182, keySlot("foo"))
+	require.Equal(t, keySlot("lock"), keySlot("{lock}:foo"))
+
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(ctx context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{{Start: 0, End: 16383, Nodes: []redis.ClusterNode{{Addr: "127.0.0.1:6379"}}}}, nil
+		},
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "{lock}:foo"
+	err := client.Del(ctx, key, notifyKey(key), queueKey(key)).Err()
+	require.NoError(t, err)
+
+	c := slotClient{client}
+	for _, locker := range []*Locker{NewLocker(c, WithBlocking()), NewLocker(c, WithQueueTracking())} {
+		lr, err := locker.LockWait(ctx, key, time.Second)
+		require.NoError(t, err)
+		require.True(t, lr.OK())
+
+		r, err := locker.Lock(ctx, key, time.Second)
+		require.NoError(t, err)
+		require.False(t, r.OK())
+
+		ok, err := lr.Unlock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	_, err = NewLocker(c, WithQueueTracking()).Lock(ctx, "lock:foo", time.Second) // no hash tag
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "CROSSSLOT")
+
+	err = client.Del(ctx, key, notifyKey(key), queueKey(key)).Err()
+	require.NoError(t, err)
+}
+
+func TestPreload(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock)
+
+	ctx := context.Background()
+	for _, src := range []string{locksrc, extendsrc, unlocksrc, unlocknotifysrc} {
+		clientMock.On("ScriptLoad", ctx, src).Return(redis.NewStringResult(redis.NewScript(src).Hash(), nil)).Once()
+	}
+	err := locker.Preload(ctx)
+	require.NoError(t, err)
+	clientMock.AssertExpectations(t)
+
+	key := "key"
+	ttl := time.Second
+	keys := []string{key}
+	e := errors.New("NOSCRIPT No matching script. Please use EVAL.")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Once()
+	clientMock.On("ScriptLoad", ctx, locksrc).Return(redis.NewStringResult(lockscr.Hash(), nil)).Once()
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	require.Equal(t, uint64(1), locker.Stats().NoScriptFallbacks)
+
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, lr.value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-4), nil)).Once()
+	r, err := lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+	clientMock.AssertExpectations(t)
+
+	clientMock = &ClientMock{}
+	locker = NewLocker(clientMock)
+	clientMock.On("ScriptLoad", ctx, mock.Anything).Return(redis.NewStringResult("", e))
+	err = locker.Preload(ctx)
+	require.ErrorIs(t, err, e)
+}
+
+func TestNoScriptReload(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock)
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	keys := []string{key}
+	value := "value"
+	e := errors.New("NOSCRIPT No matching script. Please use EVAL.")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Once()
+	clientMock.On("ScriptLoad", ctx, locksrc).Return(redis.NewStringResult(lockscr.Hash(), nil)).Once()
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	lr, err := locker.LockWithValue(ctx, key, value, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+
+	clientMock.On("EvalSha", ctx, unlockscr.Hash(), keys, value).Return(redis.NewCmdResult(nil, e)).Once()
+	clientMock.On("ScriptLoad", ctx, unlocksrc).Return(redis.NewStringResult(unlockscr.Hash(), nil)).Once()
+	clientMock.On("EvalSha", ctx, unlockscr.Hash(), keys, value).Return(redis.NewCmdResult(int64(1), nil)).Once()
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), locker.Stats().NoScriptFallbacks)
+	clientMock.AssertExpectations(t)
+
+	// retries once only
+	clientMock.On("EvalSha", ctx, unlockscr.Hash(), keys, value).Return(redis.NewCmdResult(nil, e)).Twice()
+	clientMock.On("ScriptLoad", ctx, unlocksrc).Return(redis.NewStringResult(unlockscr.Hash(), nil)).Once()
+	_, err = lr.Unlock(ctx)
+	require.ErrorIs(t, err, e)
+
+	le := errors.New("load error")
+	clientMock.On("EvalSha", ctx, unlockscr.Hash(), keys, value).Return(redis.NewCmdResult(nil, e)).Once()
+	clientMock.On("ScriptLoad", ctx, unlocksrc).Return(redis.NewStringResult("", le)).Once()
+	_, err = lr.Unlock(ctx)
+	require.ErrorIs(t, err, le)
+	clientMock.AssertExpectations(t)
+}
+
+func TestWithKeyPrefix(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, "a:"+key, "b:"+key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	la := NewLocker(client, WithKeyPrefix("a:"))
+	lb := NewLocker(client, WithKeyPrefix("b:"))
+	lr, err := la.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	require.Equal(t, key, lr.Key())
+	v, err := client.Get(ctx, "a:"+key).Result()
+	require.NoError(t, err)
+	require.Equal(t, lr.Token(), v)
+
+	lr2, err := lb.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr2.OK())
+
+	lr3, err := la.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr3.OK())
+
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = lr2.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestWithTokenLength(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock, WithRandReader(strings.NewReader("qwertyqw")), WithTokenLength(8))
+	lock, err := locker.NewLock("key")
+	require.NoError(t, err)
+	require.Equal(t, "cXdlcnR5cXc", lock.Token())
+
+	locker = NewLocker(clientMock, WithRandReader(strings.NewReader("qwe")), WithTokenLength(8))
+	_, err = locker.NewLock("key")
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	for _, n := range []int{0, -1} {
+		locker = NewLocker(clientMock, WithTokenLength(n))
+		lock, err = locker.NewLock("key")
+		require.NoError(t, err)
+		require.Len(t, lock.Token(), 22) // the default length, base64 encoded
+	}
+
+	locker = &Locker{}
+	_, err = locker.randomString()
+	require.Equal(t, ErrInvalidOption, err)
+}
+
+func TestWithDebugLog(t *testing.T) {
+	clientMock := &ClientMock{}
+	logger := &LoggerMock{}
+	locker := NewLocker(clientMock, WithLogger(logger), WithDebugLog())
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	keys := []string{key}
+	value := "secret-value"
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	lr, err := locker.LockWithValue(ctx, key, value, ttl)
+	require.NoError(t, err)
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Once()
+	_, err = lr.Lock.Lock(ctx, ttl)
+	require.Error(t, err)
+
+	clientMock.On("EvalSha", ctx, unlockscr.Hash(), keys, value).Return(redis.NewCmdResult(int64(1), nil)).Once()
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, logger.lines, 3)
+	owner := lr.OwnerHash()
+	require.True(t, strings.HasPrefix(logger.lines[0], `locker: acquire of key "key" by owner `+owner+" returned -3 in "))
+	require.True(t, strings.HasPrefix(logger.lines[1], `locker: lock of key "key" by owner `+owner+" failed after "))
+	require.True(t, strings.HasSuffix(logger.lines[1], ": locker: lock failed: redis error"))
+	require.True(t, strings.HasPrefix(logger.lines[2], `locker: unlock of key "key" by owner `+owner+" returned 1 in "))
+	for _, line := range logger.lines {
+		require.NotContains(t, line, value)
+	}
+
+	logger = &LoggerMock{}
+	locker = NewLocker(clientMock, WithLogger(logger))
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	_, err = locker.LockWithValue(ctx, key, value, ttl)
+	require.NoError(t, err)
+	require.Len(t, logger.lines, 0)
 }
@@ -0,0 +1,25 @@
+local res = {}
+local ok = true
+for i, key in ipairs(KEYS) do
+	local token = redis.call("get", key)
+	if token == false then
+		res[i] = -3
+	elseif token == ARGV[1] then
+		res[i] = -4
+	else
+		res[i] = redis.call("pttl", key)
+		ok = false
+	end
+end
+if ok then
+	for i, key in ipairs(KEYS) do
+		redis.call("set", key, ARGV[1], "px", ARGV[i + 1])
+	end
+else
+	for i in ipairs(KEYS) do
+		if res[i] == -3 or res[i] == -4 then
+			res[i] = -5
+		end
+	end
+end
+return res
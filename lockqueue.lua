@@ -0,0 +1,17 @@
+local token = redis.call("get", KEYS[1])
+if token == false then
+	redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])
+	redis.call("zrem", KEYS[2], ARGV[1])
+	return {-3, 0}
+end
+if token == ARGV[1] then
+	redis.call("pexpire", KEYS[1], ARGV[2])
+	redis.call("zrem", KEYS[2], ARGV[1])
+	return {-4, 0}
+end
+local t = redis.call("time")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call("zremrangebyscore", KEYS[2], "-inf", now - tonumber(ARGV[3]))
+redis.call("zadd", KEYS[2], now, ARGV[1])
+redis.call("pexpire", KEYS[2], ARGV[3])
+return {redis.call("pttl", KEYS[1]), redis.call("zcard", KEYS[2])}
\ No newline at end of file
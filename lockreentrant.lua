@@ -0,0 +1,12 @@
+local token = redis.call("hget", KEYS[1], "token")
+if token == false then
+	redis.call("hset", KEYS[1], "token", ARGV[1], "count", 1)
+	redis.call("pexpire", KEYS[1], ARGV[2])
+	return -3
+end
+if token == ARGV[1] then
+	redis.call("hincrby", KEYS[1], "count", 1)
+	redis.call("pexpire", KEYS[1], ARGV[2])
+	return -4
+end
+return redis.call("pttl", KEYS[1])
@@ -0,0 +1,33 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockset.lua
+var locksetsrc string
+var locksetscr = redis.NewScript(locksetsrc)
+
+// LockAndSet creates and applies new lock, and sets companionKey to companionValue atomically
+// with applying the lock, e.g. to lock "job:1" and set "job:1:status" to "RUNNING".
+// If the lock is held by someone else, companionKey is left intact. The companionKey is used as is,
+// it has no TTL, and with Redis Cluster it must hash to the same slot as the key.
+// Returns ErrInvalidOption with WithReentrant, which LockAndSet does not support.
+func (locker *Locker) LockAndSet(ctx context.Context, key string, ttl time.Duration, companionKey string, companionValue string) (LockResult, error) {
+	r := LockResult{}
+	if locker.reentrant {
+		return r, ErrInvalidOption
+	}
+	value, err := locker.newValue(ctx, key)
+	if err != nil {
+		return r, err
+	}
+	r.Lock = locker.newLock(key, value)
+	r.Result, err = r.Lock.apply(ctx, "lock", locksetscr, []string{locker.redisKey(key), companionKey}, ttl, companionValue)
+	r.Lock.cleanup(ctx, err)
+	return r, err
+}
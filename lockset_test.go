@@ -0,0 +1,49 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockAndSet(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "job:1"
+	companionKey := "job:1:status"
+	err := client.Del(ctx, key, companionKey).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	lr, err := locker.LockAndSet(ctx, key, ttl, companionKey, "RUNNING")
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+	v, err := client.Get(ctx, companionKey).Result()
+	require.NoError(t, err)
+	require.Equal(t, "RUNNING", v)
+
+	lr2, err := locker.LockAndSet(ctx, key, ttl, companionKey, "PENDING")
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+	v, err = client.Get(ctx, companionKey).Result()
+	require.NoError(t, err)
+	require.Equal(t, "RUNNING", v)
+
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	err = client.Del(ctx, companionKey).Err()
+	require.NoError(t, err)
+}
+
+func TestLockAndSetReentrant(t *testing.T) {
+	_, err := NewLocker(&ClientMock{}, WithReentrant()).LockAndSet(context.Background(), "key", time.Second, "status", "RUNNING")
+	require.Equal(t, ErrInvalidOption, err)
+}
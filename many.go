@@ -0,0 +1,129 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockmany.lua
+var lockmanysrc string
+var lockmanyscr = redis.NewScript(lockmanysrc)
+
+//go:embed unlockmany.lua
+var unlockmanysrc string
+var unlockmanyscr = redis.NewScript(unlockmanysrc)
+
+// MultiLockResult contains results of applying locks of many keys with the same value.
+type MultiLockResult struct {
+	locker *Locker
+	keys   []string
+	value  string
+	// Results of applying the lock of each key. If any lock is held by someone else, no lock is applied,
+	// and the results of the other keys are ResultNotApplied.
+	Results map[string]Result
+}
+
+// OK is success flag of applying locks of all keys.
+func (r MultiLockResult) OK() bool {
+	if len(r.Results) == 0 {
+		return false
+	}
+	for _, v := range r.Results {
+		if !v.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// TTL of the longest lock held by someone else, if any lock is, otherwise 0,
+// so all locks of the keys may be applied after the TTL.
+func (r MultiLockResult) TTL() time.Duration {
+	var ttl time.Duration
+	for _, v := range r.Results {
+		if !v.OK() && v.TTL() > ttl {
+			ttl = v.TTL()
+		}
+	}
+	return ttl
+}
+
+// Unlock releases locks of all keys held by the same owner, returns the number of released locks.
+func (r MultiLockResult) Unlock(ctx context.Context) (int, error) {
+	if len(r.keys) == 0 {
+		return 0, nil
+	}
+	keys := make([]string, len(r.keys))
+	for i, key := range r.keys {
+		keys[i] = r.locker.redisKey(key)
+	}
+	v, err := r.locker.run(ctx, "unlock", unlockmanyscr, keys, r.value)
+	return int(v), err
+}
+
+// LockMulti creates and applies locks of many keys with the same value and TTL in a single atomic operation,
+// as LockManyTTL does: either all locks are applied, or none if any lock is held by someone else,
+// so locking several resources never deadlocks. If the locks are not applied, MultiLockResult.TTL
+// is the TTL of the blocking locks, and the results of the other keys are ResultNotApplied.
+// With Redis Cluster all keys must hash to the same slot, e.g. using hash tags "{job}:1" and "{job}:2".
+// Returns ErrInvalidOption with WithReentrant, as LockManyTTL does.
+func (locker *Locker) LockMulti(ctx context.Context, keys []string, ttl time.Duration) (MultiLockResult, error) {
+	ttls := make(map[string]time.Duration, len(keys))
+	for _, key := range keys {
+		ttls[key] = ttl
+	}
+	return locker.LockManyTTL(ctx, ttls)
+}
+
+// LockManyTTL creates and applies locks of many keys with the same value, each with its own TTL,
+// in a single atomic operation: either all locks are applied, or none if any lock is held by someone else.
+// With Redis Cluster all keys must hash to the same slot, e.g. using hash tags "{job}:1" and "{job}:2".
+// Returns ErrInvalidOption with WithReentrant, which locks of many keys do not support.
+func (locker *Locker) LockManyTTL(ctx context.Context, ttls map[string]time.Duration) (MultiLockResult, error) {
+	r := MultiLockResult{locker: locker}
+	if locker.reentrant {
+		return r, ErrInvalidOption
+	}
+	r.keys = make([]string, 0, len(ttls))
+	for key := range ttls {
+		r.keys = append(r.keys, key)
+	}
+	sort.Strings(r.keys)
+	value, err := locker.newValue(ctx, r.keys...)
+	if err != nil {
+		r.keys = nil
+		return r, err
+	}
+	r.value = value
+	keys := make([]string, len(r.keys))
+	args := make([]interface{}, len(r.keys)+1)
+	args[0] = value
+	for i, key := range r.keys {
+		keys[i] = locker.redisKey(key)
+		args[i+1] = locker.ms(ttls[key])
+	}
+	res, err := locker.eval(ctx, "lock", lockmanyscr, keys, args...)
+	if err != nil {
+		return r, err
+	}
+	arr, ok := res.([]interface{})
+	if !ok || len(arr) != len(keys) {
+		return r, ErrInvalidResponse
+	}
+	r.Results = make(map[string]Result, len(keys))
+	for i, key := range r.keys {
+		v, ok := arr[i].(int64)
+		if !ok {
+			return r, ErrInvalidResponse
+		}
+		if Result(v) == ResultNoTTL {
+			return r, ErrKeyNameClash
+		}
+		r.Results[key] = Result(v)
+	}
+	return r, nil
+}
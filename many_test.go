@@ -0,0 +1,120 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockManyTTL(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.Del(ctx, "key1", "key2").Err()
+	require.NoError(t, err)
+
+	ttls := map[string]time.Duration{"key1": time.Second, "key2": 10 * time.Second}
+	locker := NewLocker(client)
+
+	r, err := locker.LockManyTTL(ctx, ttls)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.True(t, r.Results["key1"].Acquired())
+	require.True(t, r.Results["key2"].Acquired())
+	for key, ttl := range ttls {
+		pttl, err := client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.True(t, pttl > ttl/2 && pttl <= ttl)
+	}
+
+	r2, err := locker.LockManyTTL(ctx, map[string]time.Duration{"key0": time.Second, "key2": time.Second})
+	require.NoError(t, err)
+	require.False(t, r2.OK())
+	require.Equal(t, ResultNotApplied, r2.Results["key0"])
+	require.False(t, r2.Results["key0"].OK())
+	require.True(t, r2.Results["key2"].TTL() > time.Second)
+	err = client.Get(ctx, "key0").Err()
+	require.Equal(t, redis.Nil, err)
+
+	n, err := r2.Unlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	n, err = r.Unlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	c, err := client.Exists(ctx, "key1", "key2").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), c)
+
+	r, err = locker.LockManyTTL(ctx, map[string]time.Duration{"key1": time.Second})
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	r2, err = locker.LockManyTTL(ctx, map[string]time.Duration{"key1": time.Second, "key2": time.Second})
+	require.NoError(t, err)
+	require.False(t, r2.OK())
+	require.Equal(t, ResultNotApplied, r2.Results["key2"])
+	require.False(t, r2.Results["key1"].OK())
+	c, err = client.Exists(ctx, "key2").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), c)
+	_, err = r.Unlock(ctx)
+	require.NoError(t, err)
+
+	r, err = locker.LockManyTTL(ctx, nil)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+}
+
+func TestLockMulti(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	keys := []string{"key1", "key2", "key3"}
+	err := client.Del(ctx, keys...).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+	r, err := locker.LockMulti(ctx, keys[:2], ttl)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.Equal(t, time.Duration(0), r.TTL())
+
+	r2, err := locker.LockMulti(ctx, keys[1:], ttl)
+	require.NoError(t, err)
+	require.False(t, r2.OK())
+	require.True(t, r2.TTL() > 0 && r2.TTL() <= ttl)
+	require.False(t, r2.Results["key2"].OK())
+	require.True(t, r2.Results["key2"].TTL() > 0)
+	require.False(t, r2.Results["key3"].OK())
+	require.Equal(t, ResultNotApplied, r2.Results["key3"])
+	n, err := client.Exists(ctx, "key3").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	released, err := r.Unlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, released)
+
+	r2, err = locker.LockMulti(ctx, keys[1:], ttl)
+	require.NoError(t, err)
+	require.True(t, r2.OK())
+	released, err = r2.Unlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, released)
+}
+
+func TestLockManyReentrant(t *testing.T) {
+	ctx := context.Background()
+	locker := NewLocker(&ClientMock{}, WithReentrant())
+	_, err := locker.LockManyTTL(ctx, map[string]time.Duration{"key1": time.Second, "key2": time.Second})
+	require.Equal(t, ErrInvalidOption, err)
+	_, err = locker.LockMulti(ctx, []string{"key1", "key2"}, time.Second)
+	require.Equal(t, ErrInvalidOption, err)
+}
@@ -0,0 +1,126 @@
+// Package memory provides in-memory gateway for locker, e.g. for tests and single-node deployments.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	locker "github.com/da440dil/go-locker"
+)
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Gateway is in-memory implementation of locker.Gateway, safe for concurrent use.
+type Gateway struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	now     func() time.Time
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Option is function returned by functions for setting gateway options.
+type Option func(*Gateway)
+
+// WithClock sets the function returning the current time, time.Now by default.
+func WithClock(now func() time.Time) Option {
+	return func(gw *Gateway) {
+		gw.now = now
+	}
+}
+
+// New creates new gateway and starts the janitor deleting expired locks every interval,
+// if the interval is greater than 0. Use Close to stop the janitor.
+func New(interval time.Duration, options ...Option) *Gateway {
+	gw := &Gateway{
+		entries: make(map[string]entry),
+		now:     time.Now,
+		done:    make(chan struct{}),
+	}
+	for _, option := range options {
+		option(gw)
+	}
+	if interval > 0 {
+		go gw.janitor(interval)
+	}
+	return gw
+}
+
+func (gw *Gateway) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-gw.done:
+			return
+		case <-ticker.C:
+			gw.deleteExpired()
+		}
+	}
+}
+
+func (gw *Gateway) deleteExpired() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	now := gw.now()
+	for key, e := range gw.entries {
+		if !now.Before(e.expiresAt) {
+			delete(gw.entries, key)
+		}
+	}
+}
+
+// Close stops the janitor, it is safe to call Close more than once.
+func (gw *Gateway) Close() {
+	gw.once.Do(func() {
+		close(gw.done)
+	})
+}
+
+// Lock applies the lock of the key with the value if it is not held by anyone,
+// or extends the lock TTL if it is held with the same value, the same as the Redis lock script does.
+// Returns locker.ErrInvalidTTL if the TTL is less than a millisecond.
+func (gw *Gateway) Lock(ctx context.Context, key, value string, ttl time.Duration) (locker.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if ttl < time.Millisecond {
+		return 0, locker.ErrInvalidTTL
+	}
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	now := gw.now()
+	expiresAt := now.Add(ttl.Truncate(time.Millisecond))
+	e, ok := gw.entries[key]
+	if !ok || !now.Before(e.expiresAt) {
+		gw.entries[key] = entry{value: value, expiresAt: expiresAt}
+		return locker.ResultAcquired, nil
+	}
+	if e.value == value {
+		gw.entries[key] = entry{value: value, expiresAt: expiresAt}
+		return locker.ResultExtended, nil
+	}
+	return locker.Result(e.expiresAt.Sub(now) / time.Millisecond), nil
+}
+
+// Unlock releases the lock of the key if it is held with the value, and reports whether it was released.
+func (gw *Gateway) Unlock(ctx context.Context, key, value string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	e, ok := gw.entries[key]
+	if !ok || !gw.now().Before(e.expiresAt) || e.value != value {
+		return false, nil
+	}
+	delete(gw.entries, key)
+	return true, nil
+}
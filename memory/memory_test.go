@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	locker "github.com/da440dil/go-locker"
+	"github.com/stretchr/testify/require"
+)
+
+var _ locker.Gateway = (*Gateway)(nil)
+
+func TestGateway(t *testing.T) {
+	now := time.Now()
+	gw := New(0, WithClock(func() time.Time { return now }))
+	defer gw.Close()
+
+	ctx := context.Background()
+	key := "key"
+	ttl := 100 * time.Millisecond
+	r, err := gw.Lock(ctx, key, "value", ttl)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+
+	r, err = gw.Lock(ctx, key, "value", ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	now = now.Add(40 * time.Millisecond)
+	r, err = gw.Lock(ctx, key, "other", ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.Equal(t, 60*time.Millisecond, r.TTL())
+
+	ok, err := gw.Unlock(ctx, key, "other")
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = gw.Unlock(ctx, key, "value")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	r, err = gw.Lock(ctx, key, "other", ttl)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+	now = now.Add(ttl)
+	ok, err = gw.Unlock(ctx, key, "other")
+	require.NoError(t, err)
+	require.False(t, ok)
+	r, err = gw.Lock(ctx, key, "value", ttl)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+
+	_, err = gw.Lock(ctx, key, "value", 0)
+	require.Equal(t, locker.ErrInvalidTTL, err)
+	_, err = gw.Lock(ctx, key, "value", -ttl)
+	require.Equal(t, locker.ErrInvalidTTL, err)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = gw.Lock(cctx, key, "value", ttl)
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestGatewayJanitor(t *testing.T) {
+	gw := New(10 * time.Millisecond)
+	defer gw.Close()
+
+	ctx := context.Background()
+	_, err := gw.Lock(ctx, "key", "value", 20*time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	gw.mu.Lock()
+	require.Len(t, gw.entries, 0)
+	gw.mu.Unlock()
+	gw.Close()
+}
+
+func TestGatewayConcurrent(t *testing.T) {
+	gw := New(time.Millisecond)
+	defer gw.Close()
+	lkr := locker.NewLockerGateway(gw)
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	acquired := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lr, err := lkr.Lock(ctx, "key", time.Second)
+			require.NoError(t, err)
+			if lr.OK() {
+				mu.Lock()
+				acquired++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 1, acquired)
+}
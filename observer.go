@@ -0,0 +1,44 @@
+package locker
+
+import "time"
+
+// Observer is notified of outcomes of applying and releasing locks, e.g. to count acquired and contended locks
+// and errors, and to measure latency. Methods are called synchronously after each Redis round trip,
+// so they must be fast and safe for concurrent use.
+type Observer interface {
+	// LockAttempt is called after applying or extending the lock of the key by Locker.Lock and Lock.Lock.
+	// The acquired flag is set if the lock is applied or extended, ttl is the TTL of the lock if it is,
+	// otherwise the TTL of the lock held by someone else, d is the duration of the attempt.
+	// It is also called with the error, zero TTL and duration if creating the lock value fails, see Stats.TokenErrors.
+	LockAttempt(key string, acquired bool, ttl time.Duration, d time.Duration, err error)
+	// UnlockAttempt is called after releasing the lock of the key by Lock.Unlock.
+	// The released flag is set if the lock was held by the same owner, d is the duration of the attempt.
+	UnlockAttempt(key string, released bool, d time.Duration, err error)
+}
+
+// WithObserver sets the observer of applying and releasing locks. By default outcomes are not observed.
+func WithObserver(observer Observer) Option {
+	return func(locker *Locker) {
+		if observer == nil {
+			observer = noopObserver{}
+		}
+		locker.observer = observer
+	}
+}
+
+// noopObserver is Observer doing nothing.
+type noopObserver struct{}
+
+func (noopObserver) LockAttempt(key string, acquired bool, ttl time.Duration, d time.Duration, err error) {
+}
+
+func (noopObserver) UnlockAttempt(key string, released bool, d time.Duration, err error) {}
+
+// observeLock notifies the observer of the result of applying the lock of the key with the TTL started at start.
+func (locker *Locker) observeLock(key string, r Result, ttl time.Duration, start time.Time, err error) {
+	d := time.Since(start)
+	if err == nil && !r.OK() {
+		ttl = r.TTL()
+	}
+	locker.observer.LockAttempt(key, err == nil && r.OK(), ttl, d, err)
+}
@@ -0,0 +1,85 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type ObserverMock struct {
+	mock.Mock
+}
+
+func (m *ObserverMock) LockAttempt(key string, acquired bool, ttl time.Duration, d time.Duration, err error) {
+	m.Called(key, acquired, ttl, d, err)
+}
+
+func (m *ObserverMock) UnlockAttempt(key string, released bool, d time.Duration, err error) {
+	m.Called(key, released, d, err)
+}
+
+func TestWithObserver(t *testing.T) {
+	clientMock := &ClientMock{}
+	observerMock := &ObserverMock{}
+	locker := NewLocker(clientMock, WithObserver(observerMock))
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	keys := []string{key}
+	value := "value"
+	anyDuration := mock.AnythingOfType("time.Duration")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	observerMock.On("LockAttempt", key, true, ttl, anyDuration, nil).Once()
+	lr, err := locker.LockWithValue(ctx, key, value, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(42), nil)).Once()
+	observerMock.On("LockAttempt", key, false, 42*time.Millisecond, anyDuration, nil).Once()
+	r, err := lr.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Once()
+	observerMock.On("LockAttempt", key, false, ttl, anyDuration, mock.MatchedBy(func(err error) bool {
+		return errors.Is(err, e)
+	})).Once()
+	_, err = lr.Lock.Lock(ctx, ttl)
+	require.ErrorIs(t, err, e)
+
+	clientMock.On("EvalSha", ctx, unlockscr.Hash(), keys, value).Return(redis.NewCmdResult(int64(1), nil)).Once()
+	observerMock.On("UnlockAttempt", key, true, anyDuration, nil).Once()
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	clientMock.AssertExpectations(t)
+	observerMock.AssertExpectations(t)
+}
+
+func TestWithObserverTokenError(t *testing.T) {
+	clientMock := &ClientMock{}
+	observerMock := &ObserverMock{}
+	e := errors.New("random source error")
+	locker := NewLocker(clientMock, WithObserver(observerMock), WithRandReader(iotest.ErrReader(e)))
+
+	ctx := context.Background()
+	key := "key"
+	observerMock.On("LockAttempt", key, false, time.Duration(0), time.Duration(0), e).Twice()
+	_, err := locker.Lock(ctx, key, time.Second)
+	require.Equal(t, e, err)
+	_, err = locker.LockWait(ctx, key, time.Second)
+	require.Equal(t, e, err)
+	require.Equal(t, uint64(2), locker.Stats().TokenErrors)
+
+	clientMock.AssertExpectations(t)
+	observerMock.AssertExpectations(t)
+}
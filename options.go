@@ -0,0 +1,295 @@
+package locker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Option is function returned by functions for setting Locker options.
+type Option func(locker *Locker)
+
+// defaultCleanupTimeout is the default timeout of releasing a lock which may be applied after ctx is done.
+const defaultCleanupTimeout = 100 * time.Millisecond
+
+// defaultTokenLength is the default number of random bytes of lock values.
+const defaultTokenLength = 16
+
+// processStart is the process start time used to make counter values unique across restarts.
+var processStart = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// counter is the process-scoped counter of lock values.
+var counter uint64
+
+// WithCounterToken sets locker to create lock values from the process-scoped counter
+// instead of random bytes, values look like "prefix-kuq3x2ps0hc0-42",
+// where the middle part is the process start time.
+// Such values are easy to read, but unlike random values they are unique only
+// among processes using distinct prefixes or started at different times,
+// so two processes sharing a prefix may collide if started within the same nanosecond,
+// and anyone knowing the prefix can guess the value of a lock.
+func WithCounterToken(prefix string) Option {
+	return func(locker *Locker) {
+		locker.token = func() (string, error) {
+			n := atomic.AddUint64(&counter, 1)
+			return prefix + "-" + processStart + "-" + strconv.FormatUint(n, 10), nil
+		}
+	}
+}
+
+// WithRandReader sets the source of random bytes of lock values and of jitter set by WithRefreshJitter
+// instead of crypto/rand.Reader,
+// e.g. a deterministic reader in tests. The reader must be safe for concurrent use if the locker is shared,
+// the locker serializes reads of its own.
+func WithRandReader(r io.Reader) Option {
+	return func(locker *Locker) {
+		locker.rand = r
+	}
+}
+
+// WithTokenLength sets the number of random bytes of lock values, 16 by default. Shorter values make
+// collisions of lock values more likely, e.g. among n values of 8 bytes the probability is about n*n/2^65.
+// Zero or negative length keeps the default, as lock values must never be empty.
+func WithTokenLength(n int) Option {
+	return func(locker *Locker) {
+		if n <= 0 {
+			n = defaultTokenLength
+		}
+		locker.buf = make([]byte, n)
+	}
+}
+
+// WithCleanupTimeout sets the timeout of releasing a lock after ctx is done while the lock is being applied.
+// In this case Redis may apply the lock after all, so Locker.Lock tries to release it to avoid holding
+// the lock nobody knows about until its TTL is over. Zero or negative timeout disables releasing.
+// It is only done while creating a lock, extending an existing lock with Lock.Lock never releases it.
+func WithCleanupTimeout(timeout time.Duration) Option {
+	return func(locker *Locker) {
+		locker.cleanupTimeout = timeout
+	}
+}
+
+// WithMaxValueLength sets the maximum length in bytes of a value passed to Locker.LockWithValue.
+// Zero or negative length means no limit, which is the default. Values generated by the locker,
+// e.g. prefixed with the owner or the instance nonce, are never limited.
+func WithMaxValueLength(n int) Option {
+	return func(locker *Locker) {
+		locker.maxValueLength = n
+	}
+}
+
+// WithLogger sets the logger, by default nothing is logged.
+func WithLogger(logger Logger) Option {
+	return func(locker *Locker) {
+		locker.logger = logger
+	}
+}
+
+// WithSlowThreshold sets locker to log Redis round trips of applying and releasing a lock
+// which take longer than the threshold. Requires a logger set by WithLogger.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(locker *Locker) {
+		locker.slowThreshold = threshold
+	}
+}
+
+// WithDebugLog sets locker to log each attempt of applying, extending and releasing a lock with the key,
+// the owner hash as returned by Lock.OwnerHash, and the result of the attempt, e.g. to debug contention.
+// Lock values are never logged, as anyone knowing the value may extend and release the lock.
+// Requires a logger set by WithLogger.
+func WithDebugLog() Option {
+	return func(locker *Locker) {
+		locker.debugLog = true
+	}
+}
+
+// WithKeyEscaping sets locker to escape "%" as "%25" and ":" as "%3A" in keys before sending them to Redis,
+// so a key containing the separator never collides with keys derived from other keys, e.g. "key:notify".
+func WithKeyEscaping() Option {
+	return func(locker *Locker) {
+		locker.escapeKeys = true
+	}
+}
+
+// WithVerifyAcquire sets locker to read the value of a lock back after applying it, and return
+// ErrAcquireVerificationFailed if the value does not match, e.g. because of a proxy dropping writes.
+// It costs an extra round trip for each lock applied.
+func WithVerifyAcquire() Option {
+	return func(locker *Locker) {
+		locker.verifyAcquire = true
+	}
+}
+
+// WithStrictRefresh sets Lock.Lock to only extend the lock TTL once the lock is applied,
+// failing if the lock is lost, instead of applying the lock again. It makes refreshing
+// a lock in a loop safe, while the first Lock.Lock of a lock created by Locker.NewLock still applies it.
+func WithStrictRefresh() Option {
+	return func(locker *Locker) {
+		locker.strictRefresh = true
+	}
+}
+
+// WithOnContended sets the function called each time Locker.Lock or Locker.LockWait fails to apply a lock
+// held by someone else, with the key and the TTL of the held lock, e.g. to feed a circuit breaker.
+func WithOnContended(fn func(key string, ttl time.Duration)) Option {
+	return func(locker *Locker) {
+		locker.onContended = fn
+	}
+}
+
+// WithUnlockGuard sets Lock.Unlock to never release a lock which was lost at any point,
+// i.e. extending it failed, or it expired and was applied again, returning ErrLockLost instead.
+// In this case the lock is left to expire, because ownership of it can not be proved.
+func WithUnlockGuard() Option {
+	return func(locker *Locker) {
+		locker.unlockGuard = true
+	}
+}
+
+// WithOperationTimeout sets the timeout of each Redis round trip, so a single slow round trip
+// fails fast without waiting for the context of the operation, which may be long-lived.
+// If the timeout is over the error returned matches ErrOperationTimeout, not context.DeadlineExceeded.
+func WithOperationTimeout(timeout time.Duration) Option {
+	return func(locker *Locker) {
+		locker.opTimeout = timeout
+	}
+}
+
+// WithRetryableError sets the function reporting whether Locker.LockWait and Locker.LockWithRetry
+// retry to apply a lock after the error or return it immediately. By default only transient connection errors are retried.
+// Errors caused by the context being done are never retried. Errors are wrapped with the operation name,
+// so match them with errors.Is or errors.As.
+func WithRetryableError(fn func(err error) bool) Option {
+	return func(locker *Locker) {
+		locker.retryable = fn
+	}
+}
+
+// WithRetryMaxTTL sets Locker.LockWait to give up waiting for a lock held by someone else
+// if the TTL of the held lock is greater than maxTTL, returning the result of applying the lock without error.
+// Waiting for a long lock to be released is futile in many cases, so it is better to fail fast.
+func WithRetryMaxTTL(maxTTL time.Duration) Option {
+	return func(locker *Locker) {
+		locker.retryMaxTTL = maxTTL
+	}
+}
+
+// WithFaultInjector sets the function called before each Redis round trip with the operation name,
+// e.g. "lock", "extend" or "unlock", intended only for tests of handling failures.
+// If the function returns an error the round trip is skipped and the error is returned instead.
+// ErrLockLost simulates lost ownership: the lock is reported as held by someone else, and not released by Unlock.
+func WithFaultInjector(fn func(op string) error) Option {
+	return func(locker *Locker) {
+		locker.fault = fn
+	}
+}
+
+// WithOwnerFromContext sets the function returning the logical owner of locks from the context,
+// e.g. a request ID, so lock values are "<owner>#<token>" and show who holds a lock when debugging.
+// The random token keeps values unique, an empty owner is omitted. Locks created by Locker.NewLock
+// use the background context.
+func WithOwnerFromContext(fn func(ctx context.Context) string) Option {
+	return func(locker *Locker) {
+		locker.owner = fn
+	}
+}
+
+// WithKeyMaxLength sets the maximum length of Redis keys of locks, longer keys are replaced with
+// the first 16 bytes of the key followed by "#" and a hex encoded truncated SHA-256 hash of the key,
+// so the key stays recognizable. Keys not longer than the maximum are used as is.
+func WithKeyMaxLength(n int) Option {
+	return func(locker *Locker) {
+		locker.keyMaxLength = n
+	}
+}
+
+// WithKeyPrefix sets the prefix of Redis keys of locks, e.g. "myapp:", so locks of different applications
+// sharing Redis do not collide. Keys are passed to the locker and returned by Lock.Key without the prefix,
+// while patterns of Locker.Scan match Redis keys, so they must include the prefix.
+// The prefix is never escaped with WithKeyEscaping nor hashed with WithKeyMaxLength.
+func WithKeyPrefix(prefix string) Option {
+	return func(locker *Locker) {
+		locker.keyPrefix = prefix
+	}
+}
+
+// WithOnLost sets the function called synchronously when refreshing a lock in the background,
+// by Locker.NewWorkerLock, Locker.Lease or Lock.AutoRefresh, finds that the lock is lost,
+// before the context of the lock is canceled.
+// The function is called once per lost lock, and never for a lock released by its owner.
+func WithOnLost(fn func(key string)) Option {
+	return func(locker *Locker) {
+		locker.onLost = fn
+	}
+}
+
+// WithBlocking sets all locks created by the locker to notify waiters of Locker.LockWait on Unlock,
+// not only locks created by Locker.LockWait, so a waiter is woken as soon as any lock of the key is released.
+// The key is deleted and the notification is pushed atomically by the same script.
+func WithBlocking() Option {
+	return func(locker *Locker) {
+		locker.blocking = true
+	}
+}
+
+// instances is the process-scoped counter of lockers with instance nonces.
+var instances uint64
+
+// WithInstanceNonce sets locker to prefix lock values with a nonce unique to the locker instance as "<nonce>.",
+// guarding exclusivity against lock values colliding across lockers, e.g. with values created by WithCounterToken
+// or supplied by users. Random values are 16 bytes, so among n values the probability of a collision is
+// about n*n/2^129, e.g. less than 10^-20 for a billion values. Still, two lockers producing the same value
+// for the same key would both extend and release the same lock. The nonce is 8 random bytes followed by the
+// number of the instance within the process, so nonces of lockers of the same process never collide,
+// and nonces of lockers of different processes collide with probability about m*m/2^65 for m processes.
+func WithInstanceNonce() Option {
+	return func(locker *Locker) {
+		n := strconv.FormatUint(atomic.AddUint64(&instances, 1), 36)
+		b := make([]byte, 8)
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			locker.nonce = processStart + "-" + n
+			return
+		}
+		locker.nonce = base64.RawURLEncoding.EncodeToString(b) + "-" + n
+	}
+}
+
+// WithArgFromContext sets the function returning the argument derived from the context, e.g. a tenant or trace ID,
+// which is appended to arguments of every script applying, extending or releasing a lock, so it is the last one:
+// ARGV[#ARGV], e.g. ARGV[3] of LockScript and ARGV[2] of UnlockScript. Default scripts ignore it,
+// it is available to custom scripts for tenant-scoped logic or audit logging.
+func WithArgFromContext(fn func(ctx context.Context) interface{}) Option {
+	return func(locker *Locker) {
+		locker.argFromCtx = fn
+	}
+}
+
+// WithRetryCount sets the number of attempts of applying a lock by Locker.LockWithRetry after the first one,
+// by default it tries until ctx is done. If attempts are over the result of the last one is returned without error.
+// The count must not be negative, see NewLockerContext.
+func WithRetryCount(count int) Option {
+	return func(locker *Locker) {
+		locker.retryCount = count
+		locker.retryLimited = true
+	}
+}
+
+// WithRetryDelay sets the delay between attempts of applying a lock by Locker.LockWithRetry, 100ms by default.
+// The delay must not be negative, see NewLockerContext.
+func WithRetryDelay(delay time.Duration) Option {
+	return func(locker *Locker) {
+		locker.retryDelay = delay
+	}
+}
+
+// WithRetryJitter sets the maximum random addition to the delay between attempts of applying a lock
+// by Locker.LockWithRetry, 50ms by default. The jitter must not be negative, see NewLockerContext.
+func WithRetryJitter(jitter time.Duration) Option {
+	return func(locker *Locker) {
+		locker.retryJitter = jitter
+	}
+}
@@ -0,0 +1,48 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockprio.lua
+var lockpriosrc string
+var lockprioscr = redis.NewScript(lockpriosrc)
+
+// LockWithPriority creates and applies new lock with the priority. If the lock is held by someone else
+// with lower priority, the lock is applied anyway, preempting the current owner, which finds it out
+// on the next Lock.Lock, which fails, or Lock.Unlock, which returns false.
+// Locks applied without priority, e.g. by Locker.Lock, are never preempted.
+// The priority is stored in the lock value as "\x00<prio>\x00<value>", generated values never contain
+// NUL bytes and Locker.LockWithValue rejects values starting with one, so no other value is taken for a priority.
+// Owners with lower priority may starve if owners with higher priority keep coming,
+// and a preempted owner may keep working for a while until it checks the lock,
+// so use priorities only if the work under the lock is safe to be interrupted.
+// Returns ErrInvalidOption if the locker is created with WithReentrant, WithEpochKey or WithGrowOnlyTTL,
+// which priority locks do not support.
+func (locker *Locker) LockWithPriority(ctx context.Context, key string, ttl time.Duration, prio int) (LockResult, error) {
+	r := LockResult{}
+	if locker.reentrant || locker.epochKey != "" || locker.growOnly {
+		return r, ErrInvalidOption
+	}
+	value, err := locker.newValue(ctx, key)
+	if err != nil {
+		return r, err
+	}
+	r.Lock = locker.newLock(key, prioValue(prio, value))
+	r.Result, err = r.Lock.apply(ctx, "lock", lockprioscr, []string{locker.redisKey(key)}, ttl, prio)
+	r.Lock.cleanup(ctx, err)
+	return r, err
+}
+
+// prioMark delimits the priority in lock values.
+const prioMark = "\x00"
+
+// prioValue creates the value of a lock with the priority.
+func prioValue(prio int, value string) string {
+	return prioMark + strconv.Itoa(prio) + prioMark + value
+}
@@ -0,0 +1,96 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockWithPriority(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 500 * time.Millisecond
+	locker := NewLocker(client)
+
+	low, err := locker.LockWithPriority(ctx, key, ttl, 1)
+	require.NoError(t, err)
+	require.True(t, low.Acquired())
+
+	r, err := low.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	same, err := locker.LockWithPriority(ctx, key, ttl, 1)
+	require.NoError(t, err)
+	require.False(t, same.OK())
+	require.True(t, same.TTL() >= 0 && same.TTL() <= ttl)
+
+	high, err := locker.LockWithPriority(ctx, key, ttl, 2)
+	require.NoError(t, err)
+	require.True(t, high.Acquired())
+
+	r, err = low.Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	ok, err := low.Unlock(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = high.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	plain, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, plain.OK())
+
+	high, err = locker.LockWithPriority(ctx, key, ttl, 2)
+	require.NoError(t, err)
+	require.False(t, high.OK())
+
+	ok, err = plain.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestLockWithPriorityValue(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 500 * time.Millisecond
+	locker := NewLocker(client, WithOwnerFromContext(func(ctx context.Context) string { return "1:owner" }))
+
+	plain, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, plain.OK())
+
+	high, err := locker.LockWithPriority(ctx, key, ttl, 2)
+	require.NoError(t, err)
+	require.False(t, high.OK()) // the owner is not taken for a priority
+
+	ok, err := plain.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = locker.LockWithValue(ctx, key, "\x001\x00value", ttl)
+	require.Equal(t, ErrInvalidValue, err)
+
+	for _, option := range []Option{WithReentrant(), WithEpochKey("epoch"), WithGrowOnlyTTL()} {
+		_, err = NewLocker(client, option).LockWithPriority(ctx, key, ttl, 1)
+		require.Equal(t, ErrInvalidOption, err)
+	}
+}
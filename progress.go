@@ -0,0 +1,35 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// Progressor is a lock extended according to the progress of the work done under it.
+type Progressor struct {
+	LockResult
+}
+
+// LockProgress creates and applies new lock with the initial TTL, the lock is extended by Progressor.Tick
+// to cover the remaining work as it is estimated by the owner, instead of extending it by a fixed TTL on timer.
+func (locker *Locker) LockProgress(ctx context.Context, key string, initialTTL time.Duration) (*Progressor, error) {
+	lr, err := locker.Lock(ctx, key, initialTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &Progressor{LockResult: lr}, nil
+}
+
+// Tick extends the lock to cover the remaining work, plus a margin for a Redis round trip and clock skew,
+// if the lock is held by the same owner, and reports whether it is. The TTL may become shorter than before,
+// so the lock is not held longer than the work needs.
+func (p *Progressor) Tick(ctx context.Context, remainingWork time.Duration) (bool, error) {
+	if remainingWork < 0 {
+		remainingWork = 0
+	}
+	r, err := p.Lock.extend(ctx, remainingWork+minRefreshMargin)
+	if err != nil {
+		return false, err
+	}
+	return r.Extended(), nil
+}
@@ -0,0 +1,52 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockProgress(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	p, err := locker.LockProgress(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, p.OK())
+
+	ok, err := p.Tick(ctx, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	pttl, err := client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 10*time.Second && pttl <= 10*time.Second+minRefreshMargin)
+
+	ok, err = p.Tick(ctx, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+	pttl, err = client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 0 && pttl <= 100*time.Millisecond+minRefreshMargin)
+
+	p2, err := locker.LockProgress(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, p2.OK())
+	ok, err = p2.Tick(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = p.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1 @@
+return redis.call("pttl", KEYS[1])
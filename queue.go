@@ -0,0 +1,78 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockqueue.lua
+var lockqueuesrc string
+var lockqueuescr = redis.NewScript(lockqueuesrc)
+
+//go:embed dequeue.lua
+var dequeuesrc string
+var dequeuescr = redis.NewScript(dequeuesrc)
+
+// queueWindow is the time a waiter is counted after its last try to apply the lock.
+const queueWindow = 2 * maxWait
+
+// WithQueueTracking sets locker to register owners failed to apply a lock in a sorted set
+// with key "<key>:queue", and report the number of waiters in LockResult.Waiters.
+// Waiters are removed when they apply the lock, when Locker.LockWait gives up,
+// or after they stop trying for a couple of seconds. Every Locker.Lock call
+// is a new waiter, so waiters are counted precisely only with Locker.LockWait,
+// and the number is best-effort telemetry, e.g. for adaptive backoff.
+// Queue tracking does not support WithReentrant, WithEpochKey and WithGrowOnlyTTL,
+// applying a lock with any of them returns ErrInvalidOption.
+func WithQueueTracking() Option {
+	return func(locker *Locker) {
+		locker.queue = true
+	}
+}
+
+// queueKey creates Redis key of the sorted set of waiters.
+func queueKey(key string) string {
+	return key + ":queue"
+}
+
+// applyQueued applies the lock registering the owner as a waiter if the lock is held by someone else.
+func (lock *Lock) applyQueued(ctx context.Context, ttl time.Duration) (Result, int, error) {
+	if !lock.locker.queueSupported() {
+		return Result(0), 0, ErrInvalidOption
+	}
+	start := time.Now()
+	key := lock.locker.redisKey(lock.key)
+	res, err := lock.locker.eval(ctx, "lock", lockqueuescr, []string{key, queueKey(key)}, lock.value, lock.locker.ms(ttl), int(queueWindow/time.Millisecond))
+	if err != nil {
+		return Result(0), 0, err
+	}
+	arr, ok := res.([]interface{})
+	if !ok || len(arr) != 2 {
+		return Result(0), 0, ErrInvalidResponse
+	}
+	v, ok := arr[0].(int64)
+	if !ok {
+		return Result(0), 0, ErrInvalidResponse
+	}
+	waiters, ok := arr[1].(int64)
+	if !ok {
+		return Result(0), 0, ErrInvalidResponse
+	}
+	r := Result(v)
+	return r, int(waiters), lock.applied(ctx, key, r, ttl, start)
+}
+
+// queueSupported reports whether queue tracking supports the lock script of the locker.
+func (locker *Locker) queueSupported() bool {
+	return !locker.queue || locker.lockScript() == lockscr
+}
+
+// dequeue removes the owner from waiters using a background context.
+func (lock *Lock) dequeue() {
+	ctx, cancel := context.WithTimeout(context.Background(), lock.locker.cleanupTimeout)
+	defer cancel()
+	_, _ = lock.locker.eval(ctx, "dequeue", dequeuescr, []string{queueKey(lock.locker.redisKey(lock.key))}, lock.value)
+}
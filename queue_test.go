@@ -0,0 +1,89 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueTracking(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key, queueKey(key), notifyKey(key)).Err()
+	require.NoError(t, err)
+
+	ttl := 10 * time.Second
+	locker := NewLocker(client, WithQueueTracking())
+
+	lr1, err := locker.LockWait(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr1.OK())
+	require.Equal(t, 0, lr1.Waiters)
+
+	lr2, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+	require.Equal(t, 1, lr2.Waiters)
+
+	waiting := make(chan LockResult)
+	go func() {
+		lr, err := locker.LockWait(ctx, key, ttl)
+		require.NoError(t, err)
+		waiting <- lr
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	n, err := client.ZCard(ctx, queueKey(key)).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	ok, err := lr1.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	lr3 := <-waiting
+	require.True(t, lr3.OK())
+
+	members, err := client.ZRange(ctx, queueKey(key), 0, -1).Result()
+	require.NoError(t, err)
+	require.Equal(t, []string{lr2.value}, members)
+
+	ctx1, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	lr4, err := locker.LockWait(ctx1, key, ttl)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, 2, lr4.Waiters)
+	members, err = client.ZRange(ctx, queueKey(key), 0, -1).Result()
+	require.NoError(t, err)
+	require.Equal(t, []string{lr2.value}, members)
+
+	ok, err = lr3.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestQueueTrackingUnsupported(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key, queueKey(key)).Err()
+	require.NoError(t, err)
+
+	for _, option := range []Option{WithReentrant(), WithEpochKey("epoch"), WithGrowOnlyTTL()} {
+		locker := NewLocker(client, WithQueueTracking(), option)
+		_, err = locker.Lock(ctx, key, time.Second)
+		require.Equal(t, ErrInvalidOption, err)
+		_, err = NewLockerContext(ctx, client, WithQueueTracking(), option)
+		require.Equal(t, ErrInvalidOption, err)
+	}
+	n, err := client.Exists(ctx, key, queueKey(key)).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+}
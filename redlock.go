@@ -0,0 +1,137 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// clockDriftFactor is the factor of the TTL which accounts for clock drift between Redis instances.
+const clockDriftFactor = 0.01
+
+// instanceTimeoutFactor is the factor of the TTL each Redis instance is given to reply while applying the lock,
+// so an unavailable instance does not consume the whole validity of the lock.
+const instanceTimeoutFactor = 0.1
+
+// RedLocker applies locks on many independent Redis instances using the Redlock algorithm,
+// so a lock survives failures of a minority of instances.
+type RedLocker struct {
+	lockers []*Locker
+	quorum  int
+}
+
+// NewRedLocker creates new locker applying locks on the instances of the clients,
+// each with the same options. A lock is applied if it is applied on the majority of instances.
+// Returns ErrNoClients if there are no clients.
+func NewRedLocker(clients []RedisClient, options ...Option) (*RedLocker, error) {
+	if len(clients) == 0 {
+		return nil, ErrNoClients
+	}
+	lockers := make([]*Locker, len(clients))
+	for i, client := range clients {
+		lockers[i] = NewLocker(client, options...)
+	}
+	return &RedLocker{lockers: lockers, quorum: len(clients)/2 + 1}, nil
+}
+
+// RedLock is a lock applied on many Redis instances.
+type RedLock struct {
+	locks  []Lock
+	quorum int
+}
+
+// RedLockResult contains new lock and result of applying it.
+type RedLockResult struct {
+	*RedLock
+	// Validity is the time the lock is held for, which is the TTL minus the time of applying the lock
+	// and the clock drift. Less than or equal to 0 if the lock is not applied.
+	Validity time.Duration
+}
+
+// OK is success flag of applying the lock.
+func (r RedLockResult) OK() bool {
+	return r.Validity > 0
+}
+
+// Lock creates and applies new lock with the same value on all instances. If the lock is not applied
+// on the majority of instances, or the TTL is over while applying it, the lock is released on all instances.
+// Returns an error only if no instance replied.
+func (rl *RedLocker) Lock(ctx context.Context, key string, ttl time.Duration) (RedLockResult, error) {
+	value, err := rl.lockers[0].newValue(ctx, key)
+	if err != nil {
+		return RedLockResult{}, err
+	}
+	lock := &RedLock{locks: make([]Lock, len(rl.lockers)), quorum: rl.quorum}
+	for i, locker := range rl.lockers {
+		lock.locks[i] = locker.newLock(key, value)
+	}
+	r := RedLockResult{RedLock: lock}
+	r.Validity, err = lock.Lock(ctx, ttl)
+	return r, err
+}
+
+// Lock applies the lock or extends its TTL on all instances, and returns the validity of the lock.
+// If the lock is not applied on the majority of instances, or the TTL is over while applying it,
+// the lock is released on all instances and the validity is less than or equal to 0.
+// Each instance is given a fraction of the TTL to reply. Returns an error only if no instance replied.
+func (lock *RedLock) Lock(ctx context.Context, ttl time.Duration) (time.Duration, error) {
+	start := time.Now()
+	n := 0
+	var errs int
+	var firstErr error
+	timeout := time.Duration(float64(ttl) * instanceTimeoutFactor)
+	for i := range lock.locks {
+		ictx, cancel := context.WithTimeout(ctx, timeout)
+		r, err := lock.locks[i].Lock(ictx, ttl)
+		cancel()
+		if err != nil {
+			errs++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if r.OK() {
+			n++
+		}
+	}
+	drift := time.Duration(float64(ttl)*clockDriftFactor) + 2*time.Millisecond
+	validity := ttl - time.Since(start) - drift
+	if n >= lock.quorum && validity > 0 {
+		return validity, nil
+	}
+	uctx, cancel := context.WithTimeout(context.Background(), ttl)
+	defer cancel()
+	_, _ = lock.Unlock(uctx)
+	if errs == len(lock.locks) {
+		return 0, firstErr
+	}
+	if validity > 0 {
+		validity = 0
+	}
+	return validity, nil
+}
+
+// Unlock releases the lock on all instances, and reports whether it was released on the majority of instances.
+// Returns an error only if no instance replied.
+func (lock *RedLock) Unlock(ctx context.Context) (bool, error) {
+	n := 0
+	var errs int
+	var firstErr error
+	for i := range lock.locks {
+		ok, err := lock.locks[i].Unlock(ctx)
+		if err != nil {
+			errs++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ok {
+			n++
+		}
+	}
+	if errs == len(lock.locks) {
+		return false, firstErr
+	}
+	return n >= lock.quorum, nil
+}
@@ -0,0 +1,96 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedLocker(t *testing.T) {
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	keys := []string{key}
+
+	newClients := func(results ...interface{}) ([]RedisClient, []*ClientMock) {
+		clients := make([]RedisClient, len(results))
+		mocks := make([]*ClientMock, len(results))
+		for i, res := range results {
+			m := &ClientMock{}
+			if err, ok := res.(error); ok {
+				m.On("EvalSha", mock.Anything, lockscr.Hash(), keys, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, err))
+			} else {
+				m.On("EvalSha", mock.Anything, lockscr.Hash(), keys, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(res, nil))
+			}
+			m.On("EvalSha", mock.Anything, unlockscr.Hash(), keys, mock.Anything).Return(redis.NewCmdResult(interface{}(int64(1)), nil))
+			clients[i], mocks[i] = m, m
+		}
+		return clients, mocks
+	}
+
+	clients, mocks := newClients(int64(-3), int64(100), int64(-3))
+	rl, err := NewRedLocker(clients)
+	require.NoError(t, err)
+	r, err := rl.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.True(t, r.Validity > 0 && r.Validity < ttl)
+	for _, m := range mocks {
+		m.AssertNumberOfCalls(t, "EvalSha", 1)
+	}
+	ok, err := r.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	clients, mocks = newClients(int64(-3), int64(100), int64(100))
+	rl, err = NewRedLocker(clients)
+	require.NoError(t, err)
+	r, err = rl.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	for _, m := range mocks {
+		m.AssertCalled(t, "EvalSha", mock.Anything, unlockscr.Hash(), keys, mock.Anything)
+	}
+
+	e := errors.New("any")
+	clients, _ = newClients(e, e, e)
+	rl, err = NewRedLocker(clients)
+	require.NoError(t, err)
+	_, err = rl.Lock(ctx, key, ttl)
+	require.ErrorIs(t, err, e)
+
+	_, err = NewRedLocker(nil)
+	require.Equal(t, ErrNoClients, err)
+}
+
+func TestRedLockerInstanceTimeout(t *testing.T) {
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	keys := []string{key}
+
+	clients := make([]RedisClient, 3)
+	for i := range clients {
+		m := &ClientMock{}
+		if i == 0 {
+			m.On("EvalSha", mock.Anything, lockscr.Hash(), keys, mock.Anything, int(ttl/time.Millisecond)).Run(func(args mock.Arguments) {
+				<-args.Get(0).(context.Context).Done()
+			}).Return(redis.NewCmdResult(nil, context.DeadlineExceeded))
+		} else {
+			m.On("EvalSha", mock.Anything, lockscr.Hash(), keys, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil))
+		}
+		m.On("EvalSha", mock.Anything, unlockscr.Hash(), keys, mock.Anything).Return(redis.NewCmdResult(int64(1), nil))
+		clients[i] = m
+	}
+	rl, err := NewRedLocker(clients)
+	require.NoError(t, err)
+	r, err := rl.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.True(t, r.Validity > ttl/2) // the unavailable instance consumed only a fraction of the TTL
+}
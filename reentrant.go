@@ -0,0 +1,49 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lockreentrant.lua
+var lockreentrantsrc string
+var lockreentrantscr = redis.NewScript(lockreentrantsrc)
+
+//go:embed extendreentrant.lua
+var extendreentrantsrc string
+var extendreentrantscr = redis.NewScript(extendreentrantsrc)
+
+//go:embed unlockreentrant.lua
+var unlockreentrantsrc string
+var unlockreentrantscr = redis.NewScript(unlockreentrantsrc)
+
+// WithReentrant sets locker to apply reentrant locks: applying a lock held by the same owner with Lock.Lock
+// increments the lock depth, and Lock.Unlock decrements it, so the lock is released when it is unlocked
+// as many times as it is locked, e.g. by nested critical sections. Extending a lock in the background,
+// e.g. by Locker.NewWorkerLock, and Lock.Heartbeat do not change the depth.
+// A lock is stored as a Redis hash of the token and the depth, so reentrant mode does not work
+// with operations reading the lock value, e.g. Lock.TTL, Locker.Scan or WithVerifyAcquire.
+// Reentrant locks support neither WithEpochKey, WithGrowOnlyTTL nor WithBlocking, applying and extending
+// a lock with any of them returns ErrInvalidOption, so does NewLockerContext.
+func WithReentrant() Option {
+	return func(locker *Locker) {
+		locker.reentrant = true
+	}
+}
+
+// reentrantSupported reports whether reentrant mode, if set, supports the other options of the locker.
+func (locker *Locker) reentrantSupported() bool {
+	return !locker.reentrant || (locker.epochKey == "" && !locker.growOnly && !locker.blocking)
+}
+
+// refresh extends the lock TTL in the background: in reentrant mode it only extends the lock held by the same owner,
+// so the lock depth does not change, otherwise it applies the lock as Lock does.
+func (lock *Lock) refresh(ctx context.Context, ttl time.Duration) (Result, error) {
+	if lock.locker.reentrant {
+		return lock.extend(ctx, ttl)
+	}
+	return lock.Lock(ctx, ttl)
+}
@@ -0,0 +1,112 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReentrant(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client, WithReentrant())
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.Acquired())
+
+	const depth = 3
+	for i := 1; i < depth; i++ {
+		r, err := lr.Lock.Lock(ctx, ttl)
+		require.NoError(t, err)
+		require.True(t, r.Extended())
+	}
+
+	ok, err := lr.Heartbeat(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	other, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, other.OK())
+	require.True(t, other.TTL() > 0)
+	ok, err = other.Unlock(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	for i := 0; i < depth; i++ {
+		n, err := client.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+		ok, err = lr.Unlock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	n, err := client.Exists(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	ok, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestReentrantWorkerLock(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client, WithReentrant())
+	wl, err := locker.NewWorkerLock(ctx, key, ttl)
+	require.NoError(t, err)
+	time.Sleep(2 * ttl) // the lock is extended in the background without changing the depth
+	err = wl.Release()
+	require.NoError(t, err)
+	n, err := client.Exists(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+}
+
+func TestReentrantUnsupported(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	for _, option := range []Option{WithEpochKey("epoch"), WithGrowOnlyTTL(), WithBlocking()} {
+		locker := NewLocker(client, WithReentrant(), option)
+		_, err = locker.Lock(ctx, key, ttl)
+		require.Equal(t, ErrInvalidOption, err)
+		lock, err := locker.NewLock(key)
+		require.NoError(t, err)
+		_, err = lock.Lock(ctx, ttl)
+		require.ErrorIs(t, err, ErrInvalidOption)
+		_, err = lock.ExtendUntil(ctx, time.Now().Add(ttl))
+		require.ErrorIs(t, err, ErrInvalidOption)
+		_, err = locker.RefreshByToken(ctx, lock.Token(), []string{key}, ttl)
+		require.Equal(t, ErrInvalidOption, err)
+		_, err = NewLockerContext(ctx, client, WithReentrant(), option)
+		require.Equal(t, ErrInvalidOption, err)
+	}
+	n, err := client.Exists(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+}
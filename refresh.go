@@ -0,0 +1,153 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"encoding/binary"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed extend.lua
+var extendsrc string
+var extendscr = redis.NewScript(extendsrc)
+
+// minRefreshMargin is the minimum time left before a lock expires when it is extended,
+// which covers a Redis round trip and clock skew.
+const minRefreshMargin = 50 * time.Millisecond
+
+// RefreshInterval returns the interval of extending a lock with the TTL,
+// which leaves time for at least one more try before the lock expires.
+// It is half of the TTL, but not less than a quarter of the TTL, and not less than a millisecond,
+// and also leaves a margin for a Redis round trip and clock skew for short TTLs.
+func RefreshInterval(ttl time.Duration) time.Duration {
+	interval := ttl / 2
+	if ttl-minRefreshMargin < interval {
+		interval = ttl - minRefreshMargin
+	}
+	if interval < ttl/4 {
+		interval = ttl / 4
+	}
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval
+}
+
+// WithRefreshJitter sets the fraction of the refresh interval by which background refreshing of locks,
+// e.g. by Locker.NewWorkerLock, is randomly scheduled earlier, so many holders do not extend locks at the same time.
+// The fraction is limited to [0, 1], refreshing is never scheduled later than RefreshInterval.
+func WithRefreshJitter(fraction float64) Option {
+	return func(locker *Locker) {
+		if fraction < 0 {
+			fraction = 0
+		}
+		if fraction > 1 {
+			fraction = 1
+		}
+		locker.refreshJitter = fraction
+	}
+}
+
+// refreshInterval returns the interval of refreshing a lock with the TTL in the background,
+// RefreshInterval reduced by random jitter set by WithRefreshJitter, read from the reader set by WithRandReader.
+func (locker *Locker) refreshInterval(ttl time.Duration) time.Duration {
+	interval := RefreshInterval(ttl)
+	if locker.refreshJitter == 0 {
+		return interval
+	}
+	var b [8]byte
+	locker.mu.Lock()
+	err := locker.readRandom(b[:])
+	locker.mu.Unlock()
+	if err != nil {
+		return interval
+	}
+	f := float64(binary.BigEndian.Uint64(b[:])>>11) / (1 << 53)
+	interval -= time.Duration(f * locker.refreshJitter * float64(interval))
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval
+}
+
+// lost calls the function set by WithOnLost.
+func (locker *Locker) lost(key string) {
+	if locker.onLost != nil {
+		locker.onLost(key)
+	}
+}
+
+// pipelineClient is redis client interface for pipelining commands.
+type pipelineClient interface {
+	Pipeline() redis.Pipeliner
+}
+
+// RefreshByToken extends TTL of the locks of the keys applied with the same value, e.g. by locks created
+// with Locker.NewLock and shared by a worker. Unlike Lock.Lock it never applies a lock which is not held.
+// Returns flags of extending the lock of each key. If the client implements Pipeline, all keys are
+// extended in a single round trip.
+func (locker *Locker) RefreshByToken(ctx context.Context, token string, keys []string, ttl time.Duration) ([]bool, error) {
+	if !locker.reentrantSupported() {
+		return nil, ErrInvalidOption
+	}
+	results := make([]Result, len(keys))
+	var err error
+	if client, ok := locker.client.(pipelineClient); ok {
+		err = locker.refreshPipeline(ctx, client, token, keys, ttl, results)
+	} else {
+		for i, key := range keys {
+			results[i], err = (&Lock{locker: locker, key: key, value: token}).extend(ctx, ttl)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	extended := make([]bool, len(keys))
+	for i, r := range results {
+		extended[i] = r.Extended()
+	}
+	return extended, nil
+}
+
+func (locker *Locker) refreshPipeline(ctx context.Context, client pipelineClient, token string, keys []string, ttl time.Duration, results []Result) error {
+	start := time.Now()
+	cmds, err := locker.execExtend(ctx, client, token, keys, ttl)
+	if err != nil && isNoScript(err) {
+		locker.noScriptFallback()
+		if err = locker.extendScript().Load(ctx, locker.client).Err(); err != nil {
+			return opError("extend", classify(err))
+		}
+		cmds, err = locker.execExtend(ctx, client, token, keys, ttl)
+	}
+	if err != nil {
+		return opError("extend", classify(err))
+	}
+	for i, cmd := range cmds {
+		v, ok := cmd.Val().(int64)
+		if !ok {
+			return ErrInvalidResponse
+		}
+		results[i] = Result(v)
+		locker.track(&Lock{locker: locker, key: keys[i], value: token}, results[i], ttl, start)
+	}
+	return nil
+}
+
+func (locker *Locker) execExtend(ctx context.Context, client pipelineClient, token string, keys []string, ttl time.Duration) ([]*redis.Cmd, error) {
+	pipe := client.Pipeline()
+	cmds := make([]*redis.Cmd, len(keys))
+	for i, key := range keys {
+		args := []interface{}{token, locker.ms(ttl)}
+		if locker.argFromCtx != nil {
+			args = append(args, locker.argFromCtx(ctx))
+		}
+		cmds[i] = pipe.EvalSha(ctx, locker.extendScript().Hash(), locker.lockKeys(locker.redisKey(key)), args...)
+	}
+	_, err := pipe.Exec(ctx)
+	return cmds, err
+}
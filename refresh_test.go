@@ -0,0 +1,112 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshByToken(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	keys := []string{"key1", "key2", "key3"}
+	err := client.Del(ctx, keys...).Err()
+	require.NoError(t, err)
+	err = client.ScriptFlush(ctx).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	token := "token"
+	locker := NewLocker(client)
+	for _, key := range keys[:2] {
+		lr, err := locker.LockWithValue(ctx, key, token, 100*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, lr.OK())
+	}
+	err = client.Set(ctx, keys[1], "value", ttl).Err()
+	require.NoError(t, err)
+
+	extended, err := locker.RefreshByToken(ctx, token, keys, ttl)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false, false}, extended)
+
+	pttl, err := client.PTTL(ctx, keys[0]).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 100*time.Millisecond)
+	n, err := client.Exists(ctx, keys[2]).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	clientMock := &ClientMock{}
+	locker = NewLocker(clientMock)
+	clientMock.On("EvalSha", ctx, extendscr.Hash(), []string{keys[0]}, token, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(-4)), nil))
+	clientMock.On("EvalSha", ctx, extendscr.Hash(), []string{keys[1]}, token, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(0)), nil))
+	extended, err = locker.RefreshByToken(ctx, token, keys[:2], ttl)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false}, extended)
+	clientMock.AssertExpectations(t)
+}
+
+func TestRefreshInterval(t *testing.T) {
+	tests := []struct {
+		ttl      time.Duration
+		interval time.Duration
+	}{
+		{time.Minute, 30 * time.Second},
+		{time.Second, 500 * time.Millisecond},
+		{120 * time.Millisecond, 60 * time.Millisecond},
+		{80 * time.Millisecond, 30 * time.Millisecond},
+		{40 * time.Millisecond, 10 * time.Millisecond},
+		{time.Millisecond, time.Millisecond},
+		{0, time.Millisecond},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.interval, RefreshInterval(tt.ttl), tt.ttl)
+	}
+}
+
+func TestWithRefreshJitter(t *testing.T) {
+	ttl := time.Second
+	locker := NewLocker(nil)
+	require.Equal(t, RefreshInterval(ttl), locker.refreshInterval(ttl))
+
+	locker = NewLocker(nil, WithRefreshJitter(0.5))
+	intervals := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		interval := locker.refreshInterval(ttl)
+		require.True(t, interval >= RefreshInterval(ttl)/2 && interval <= RefreshInterval(ttl), interval)
+		intervals[interval] = true
+	}
+	require.True(t, len(intervals) > 1)
+
+	locker = NewLocker(nil, WithRefreshJitter(2))
+	for i := 0; i < 100; i++ {
+		interval := locker.refreshInterval(ttl)
+		require.True(t, interval >= time.Millisecond && interval <= RefreshInterval(ttl), interval)
+	}
+}
+
+func TestWithRefreshJitterRandReader(t *testing.T) {
+	ttl := time.Second
+	locker1 := NewLocker(nil, WithRefreshJitter(0.5), WithRandReader(rand.New(rand.NewSource(1))))
+	locker2 := NewLocker(nil, WithRefreshJitter(0.5), WithRandReader(rand.New(rand.NewSource(1))))
+	intervals := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		interval := locker1.refreshInterval(ttl)
+		require.True(t, interval >= RefreshInterval(ttl)/2 && interval <= RefreshInterval(ttl) && interval < ttl, interval)
+		require.Equal(t, interval, locker2.refreshInterval(ttl)) // the jitter is read from the reader of the locker
+		intervals[interval] = true
+	}
+	require.True(t, len(intervals) > 1)
+
+	locker := NewLocker(nil, WithRefreshJitter(0.5), WithRandReader(iotest.ErrReader(errors.New("any"))))
+	require.Equal(t, RefreshInterval(ttl), locker.refreshInterval(ttl))
+}
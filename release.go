@@ -0,0 +1,57 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed releaseowned.lua
+var releaseownedsrc string
+var releaseownedscr = redis.NewScript(releaseownedsrc)
+
+// scanCount is the number of keys scanned by a single script call.
+const scanCount = 100
+
+// globEscaper escapes special characters of Redis glob-style patterns.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// ReleaseOwned releases locks with keys starting with the prefix and the value, e.g. all locks of a worker
+// created with Locker.NewLock or WithCounterToken, leaving locks of other owners intact.
+// Keys are scanned with SCAN in batches, and the locks of each batch are released by a single script call
+// which gets the keys of the batch as KEYS, so Redis is never blocked for long. Returns the number of released locks.
+// It scans keys of a single Redis node, so it does not work with Redis Cluster. Returns ErrUnsupported
+// if the client does not implement Scan and Pipeline.
+// The prefix is escaped as keys are with WithKeyEscaping, but never hashed with WithKeyMaxLength,
+// so locks with hashed keys match only if the prefix is within the part of the key kept before the hash.
+func (locker *Locker) ReleaseOwned(ctx context.Context, prefix string, value string) (int, error) {
+	client, ok := locker.client.(scanClient)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	if locker.escapeKeys {
+		prefix = keyEscaper.Replace(prefix)
+	}
+	match := globEscaper.Replace(locker.keyPrefix+prefix) + "*"
+	var cursor uint64
+	n := 0
+	for {
+		keys, next, err := client.Scan(ctx, cursor, match, scanCount).Result()
+		if err != nil {
+			return n, opError("release", classify(err))
+		}
+		if len(keys) != 0 {
+			deleted, err := locker.run(ctx, "release", releaseownedscr, keys, value)
+			if err != nil {
+				return n, err
+			}
+			n += int(deleted)
+		}
+		if next == 0 {
+			return n, nil
+		}
+		cursor = next
+	}
+}
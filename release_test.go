@@ -0,0 +1,87 @@
+package locker
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseOwned(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.FlushDB(ctx).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+	owned := 250
+	for i := 0; i < owned; i++ {
+		_, err = locker.LockWithValue(ctx, "job:"+strconv.Itoa(i), "worker1", ttl)
+		require.NoError(t, err)
+	}
+	_, err = locker.LockWithValue(ctx, "job:other", "worker2", ttl)
+	require.NoError(t, err)
+	_, err = locker.LockWithValue(ctx, "task:1", "worker1", ttl)
+	require.NoError(t, err)
+	err = client.HSet(ctx, "job:hash", "field", "worker1").Err()
+	require.NoError(t, err)
+
+	n, err := locker.ReleaseOwned(ctx, "job:", "worker1")
+	require.NoError(t, err)
+	require.Equal(t, owned, n)
+
+	keys, err := client.Keys(ctx, "*").Result()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"job:other", "task:1", "job:hash"}, keys)
+
+	n, err = locker.ReleaseOwned(ctx, "job:", "worker1")
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	_, err = locker.LockWithValue(ctx, "job*:1", "worker1", ttl)
+	require.NoError(t, err)
+	_, err = locker.LockWithValue(ctx, "jobs:1", "worker1", ttl)
+	require.NoError(t, err)
+	n, err = locker.ReleaseOwned(ctx, "job*:", "worker1") // the prefix is escaped
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	_, err = NewLocker(&ClientMock{}).ReleaseOwned(ctx, "job:", "worker1")
+	require.Equal(t, ErrUnsupported, err)
+}
+
+func TestReleaseOwnedKeyMaxLength(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.FlushDB(ctx).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client, WithKeyPrefix("app:"), WithKeyMaxLength(8))
+	_, err = locker.LockWithValue(ctx, "job:1", "worker1", ttl)
+	require.NoError(t, err)
+	_, err = locker.LockWithValue(ctx, "job:2", "worker1", ttl)
+	require.NoError(t, err)
+
+	n, err := locker.ReleaseOwned(ctx, "job:", "worker1") // the prefix is shorter than the max length
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	_, err = locker.LockWithValue(ctx, "jobs:queue:1", "worker1", ttl) // the key is hashed
+	require.NoError(t, err)
+	n, err = locker.ReleaseOwned(ctx, "jobs:queue:", "worker1") // the prefix is longer than the max length
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	keys, err := client.Keys(ctx, "*").Result()
+	require.NoError(t, err)
+	require.Len(t, keys, 0)
+}
@@ -0,0 +1,7 @@
+local n = 0
+for _, key in ipairs(KEYS) do
+	if redis.call("type", key).ok == "string" and redis.call("get", key) == ARGV[1] then
+		n = n + redis.call("del", key)
+	end
+end
+return n
@@ -0,0 +1,35 @@
+package locker
+
+import "time"
+
+// TTLRounding is the mode of rounding TTL of locks to milliseconds, the precision of Redis.
+type TTLRounding int
+
+const (
+	// TTLTruncate truncates TTL to milliseconds, e.g. 1500µs becomes 1ms. It is the default.
+	TTLTruncate TTLRounding = iota
+	// TTLRound rounds TTL to the nearest millisecond, halfway away from zero, e.g. 1500µs becomes 2ms.
+	TTLRound
+	// TTLCeil rounds TTL up to milliseconds, e.g. 1200µs becomes 2ms, so a lock is held at least for the TTL.
+	TTLCeil
+)
+
+// WithTTLRounding sets the mode of rounding TTL of locks to milliseconds.
+func WithTTLRounding(mode TTLRounding) Option {
+	return func(locker *Locker) {
+		locker.rounding = mode
+	}
+}
+
+// ms converts the TTL to milliseconds using the mode set by WithTTLRounding.
+func (locker *Locker) ms(ttl time.Duration) int {
+	switch locker.rounding {
+	case TTLRound:
+		ttl = ttl.Round(time.Millisecond)
+	case TTLCeil:
+		if r := ttl % time.Millisecond; r > 0 {
+			ttl += time.Millisecond - r
+		}
+	}
+	return int(ttl / time.Millisecond)
+}
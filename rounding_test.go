@@ -0,0 +1,36 @@
+package locker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTTLRounding(t *testing.T) {
+	tests := []struct {
+		ttl      time.Duration
+		truncate int
+		round    int
+		ceil     int
+	}{
+		{0, 0, 0, 0},
+		{500 * time.Microsecond, 0, 1, 1},
+		{999 * time.Microsecond, 0, 1, 1},
+		{time.Millisecond, 1, 1, 1},
+		{1200 * time.Microsecond, 1, 1, 2},
+		{1500 * time.Microsecond, 1, 2, 2},
+		{1999 * time.Microsecond, 1, 2, 2},
+		{time.Second + time.Nanosecond, 1000, 1000, 1001},
+	}
+	lockers := map[TTLRounding]*Locker{
+		TTLTruncate: NewLocker(nil),
+		TTLRound:    NewLocker(nil, WithTTLRounding(TTLRound)),
+		TTLCeil:     NewLocker(nil, WithTTLRounding(TTLCeil)),
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.truncate, lockers[TTLTruncate].ms(tt.ttl), tt.ttl)
+		require.Equal(t, tt.round, lockers[TTLRound].ms(tt.ttl), tt.ttl)
+		require.Equal(t, tt.ceil, lockers[TTLCeil].ms(tt.ttl), tt.ttl)
+	}
+}
@@ -0,0 +1,48 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// RunLocked creates and applies new lock waiting for it to be released as LockWait does until ctx is done,
+// then runs fn and releases the lock when fn returns, even if it panics. Returns the error of fn,
+// or the error of applying the lock, e.g. ctx error if ctx is done before the lock is applied,
+// or the error of Result.Err, e.g. *LockedError, if waiting is given up because of WithRetryMaxTTL.
+// The lock is released using a background context with the TTL timeout, so it is released even if ctx is done.
+func (locker *Locker) RunLocked(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lr, err := locker.LockWait(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	if err = lr.Err(); err != nil {
+		return err
+	}
+	defer func() {
+		uctx, cancel := context.WithTimeout(context.Background(), ttl)
+		defer cancel()
+		_, _ = lr.Unlock(uctx)
+	}()
+	return fn(ctx)
+}
+
+// WithLockHandle creates and applies new lock, if the lock is applied runs fn passing the lock,
+// so fn may extend the lock or read it, and releases the lock when fn returns, even if it panics.
+// Returns the error of fn, the error of applying the lock, or the error of Result.Err if the lock is not applied,
+// e.g. *LockedError if the lock is held by someone else.
+// The lock is released using a background context with the TTL timeout, so it is released even if ctx is done.
+func (locker *Locker) WithLockHandle(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context, lock *Lock) error) error {
+	lr, err := locker.Lock(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	if err = lr.Err(); err != nil {
+		return err
+	}
+	defer func() {
+		uctx, cancel := context.WithTimeout(context.Background(), ttl)
+		defer cancel()
+		_, _ = lr.Unlock(uctx)
+	}()
+	return fn(ctx, &lr.Lock)
+}
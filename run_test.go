@@ -0,0 +1,99 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLocked(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	e := errors.New("fn error")
+	err = locker.RunLocked(ctx, key, ttl, func(ctx context.Context) error {
+		n, err := client.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+		return e
+	})
+	require.Equal(t, e, err)
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+
+	lr, err := locker.Lock(ctx, key, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	called := false
+	err = locker.RunLocked(ctx, key, ttl, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, called)
+
+	lr, err = locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	ctx1, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = locker.RunLocked(ctx1, key, ttl, func(ctx context.Context) error {
+		t.Fatal("fn is called")
+		return nil
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+}
+
+func TestWithLockHandle(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	err = locker.WithLockHandle(ctx, key, ttl, func(ctx context.Context, lock *Lock) error {
+		r, err := lock.Lock(ctx, 10*ttl)
+		require.NoError(t, err)
+		require.True(t, r.Extended())
+		pttl, err := client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.True(t, pttl > ttl)
+
+		err = locker.WithLockHandle(ctx, key, ttl, func(ctx context.Context, lock *Lock) error {
+			t.Fatal("fn is called")
+			return nil
+		})
+		require.True(t, errors.Is(err, ErrLocked))
+		return nil
+	})
+	require.NoError(t, err)
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+
+	require.Panics(t, func() {
+		_ = locker.WithLockHandle(ctx, key, ttl, func(ctx context.Context, lock *Lock) error {
+			panic("fn panics")
+		})
+	})
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+}
@@ -0,0 +1,141 @@
+package locker
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed inspect.lua
+var inspectsrc string
+var inspectscr = redis.NewScript(inspectsrc)
+
+// LockInfo is the state of a lock found by Locker.Scan or Locker.Inspect.
+type LockInfo struct {
+	// Key is the Redis key of the lock.
+	Key string
+	// Held is the flag of the lock held by anyone.
+	Held bool
+	// TTL is the remaining TTL of the lock.
+	TTL time.Duration
+	// Owner is the redacted lock value: first 8 hex digits of its SHA-256 hash,
+	// it identifies the owner without exposing the value which allows to release the lock.
+	Owner string
+}
+
+// scanClient is redis client interface for scanning keys.
+type scanClient interface {
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	Pipeline() redis.Pipeliner
+}
+
+// Scan returns locks with Redis keys matching the glob-style pattern, e.g. "job:*".
+// Keys are scanned with SCAN in batches, and the TTL and the value of each batch are read
+// in a single pipeline, so Redis is never blocked for long. Keys which are not strings
+// or have no TTL are not locks, so they are skipped. It scans keys of a single Redis node,
+// so it does not work with Redis Cluster. Returns ErrUnsupported if the client does not implement
+// Scan and Pipeline.
+func (locker *Locker) Scan(ctx context.Context, pattern string) ([]LockInfo, error) {
+	client, ok := locker.client.(scanClient)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	var infos []LockInfo
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return infos, opError("scan", classify(err))
+		}
+		if len(keys) != 0 {
+			if infos, err = scanLocks(ctx, client, keys, infos); err != nil {
+				return infos, opError("scan", classify(err))
+			}
+		}
+		if next == 0 {
+			return infos, nil
+		}
+		cursor = next
+	}
+}
+
+// scanLocks reads the type, the TTL and the value of the keys in a single pipeline,
+// and appends locks among the keys to infos.
+func scanLocks(ctx context.Context, client scanClient, keys []string, infos []LockInfo) ([]LockInfo, error) {
+	pipe := client.Pipeline()
+	types := make([]*redis.StatusCmd, len(keys))
+	pttls := make([]*redis.DurationCmd, len(keys))
+	values := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		types[i] = pipe.Type(ctx, key)
+		pttls[i] = pipe.PTTL(ctx, key)
+		values[i] = pipe.Get(ctx, key)
+	}
+	// The error of the pipeline is the first error of its commands, e.g. GET of a key which is not a string,
+	// so errors are checked for each command.
+	_, _ = pipe.Exec(ctx)
+	for i, key := range keys {
+		typ, err := types[i].Result()
+		if err != nil {
+			return infos, err
+		}
+		if typ != "string" {
+			continue
+		}
+		pttl, err := pttls[i].Result()
+		if err != nil {
+			return infos, err
+		}
+		if pttl < 0 {
+			continue
+		}
+		value, err := values[i].Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return infos, err
+		}
+		infos = append(infos, LockInfo{Key: key, Held: true, TTL: pttl, Owner: ownerHash(value)})
+	}
+	return infos, nil
+}
+
+// Inspect returns the state of the lock of the key in a single round trip.
+// If the lock is not held, only the key is set.
+func (locker *Locker) Inspect(ctx context.Context, key string) (LockInfo, error) {
+	info := LockInfo{Key: locker.redisKey(key)}
+	res, err := locker.eval(ctx, "inspect", inspectscr, []string{info.Key})
+	if err != nil {
+		return info, err
+	}
+	arr, ok := res.([]interface{})
+	if !ok || len(arr) != 2 {
+		return info, ErrInvalidResponse
+	}
+	pttl, ok := arr[0].(int64)
+	if !ok {
+		return info, ErrInvalidResponse
+	}
+	if arr[1] == nil {
+		return info, nil
+	}
+	value, ok := arr[1].(string)
+	if !ok {
+		return info, ErrInvalidResponse
+	}
+	info.Held = true
+	info.TTL = time.Duration(pttl) * time.Millisecond
+	info.Owner = ownerHash(value)
+	return info, nil
+}
+
+// ownerHash redacts the lock value.
+func ownerHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:4])
+}
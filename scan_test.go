@@ -0,0 +1,81 @@
+package locker
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.FlushDB(ctx).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+	n := 150
+	for i := 0; i < n; i++ {
+		_, err = locker.LockWithValue(ctx, "job:"+strconv.Itoa(i), "worker1", ttl)
+		require.NoError(t, err)
+	}
+	_, err = locker.LockWithValue(ctx, "task:1", "worker1", ttl)
+	require.NoError(t, err)
+	err = client.HSet(ctx, "job:hash", "field", "worker1").Err()
+	require.NoError(t, err)
+	err = client.Set(ctx, "job:nottl", "worker1", 0).Err()
+	require.NoError(t, err)
+
+	infos, err := locker.Scan(ctx, "job:*")
+	require.NoError(t, err)
+	require.Len(t, infos, n)
+	for _, info := range infos {
+		require.Regexp(t, `^job:\d+$`, info.Key)
+		require.True(t, info.TTL > 0 && info.TTL <= ttl)
+		require.Equal(t, ownerHash("worker1"), info.Owner)
+		require.Len(t, info.Owner, 8)
+	}
+
+	infos, err = locker.Scan(ctx, "none:*")
+	require.NoError(t, err)
+	require.Len(t, infos, 0)
+
+	_, err = NewLocker(&ClientMock{}).Scan(ctx, "job:*")
+	require.Equal(t, ErrUnsupported, err)
+}
+
+func TestInspect(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	locker := NewLocker(client)
+
+	info, err := locker.Inspect(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, LockInfo{Key: key}, info)
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	info, err = locker.Inspect(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, key, info.Key)
+	require.True(t, info.Held)
+	require.True(t, info.TTL > 0 && info.TTL <= ttl)
+	require.Equal(t, lr.OwnerHash(), info.Owner)
+
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+}
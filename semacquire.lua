@@ -0,0 +1,20 @@
+local t = redis.call("time")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local ttl = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call("zremrangebyscore", KEYS[1], "-inf", now)
+local res
+local count = redis.call("zcard", KEYS[1])
+if redis.call("zscore", KEYS[1], ARGV[1]) then
+	redis.call("zadd", KEYS[1], now + ttl, ARGV[1])
+	res = {-4, limit - count}
+elseif count < limit then
+	redis.call("zadd", KEYS[1], now + ttl, ARGV[1])
+	res = {-3, limit - count - 1}
+else
+	local first = redis.call("zrange", KEYS[1], 0, 0, "withscores")
+	return {tonumber(first[2]) - now, 0}
+end
+local last = redis.call("zrange", KEYS[1], -1, -1, "withscores")
+redis.call("pexpireat", KEYS[1], last[2])
+return res
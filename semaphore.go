@@ -0,0 +1,82 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed semacquire.lua
+var semacquiresrc string
+var semacquirescr = redis.NewScript(semacquiresrc)
+
+//go:embed semrelease.lua
+var semreleasesrc string
+var semreleasescr = redis.NewScript(semreleasesrc)
+
+// Semaphore allows up to a limit of holders of the same key at the same time. Each Semaphore is a single holder,
+// create a Semaphore for each worker. Holders are stored in a Redis sorted set scored by expiry time,
+// so holders which are not released expire after the TTL.
+type Semaphore struct {
+	locker *Locker
+	key    string
+	value  string
+	limit  int
+}
+
+// SemaphoreResult contains result of acquiring a semaphore.
+type SemaphoreResult struct {
+	// Result of acquiring the semaphore, Result.TTL is the time until the earliest holder expires
+	// if the semaphore is not acquired.
+	Result
+	// Remaining is the number of holders which may acquire the semaphore after this one.
+	Remaining int
+}
+
+// NewSemaphore creates new semaphore of the key for up to limit holders without acquiring it.
+// Returns ErrInvalidLimit if the limit is less than 1.
+func (locker *Locker) NewSemaphore(key string, limit int) (*Semaphore, error) {
+	if limit < 1 {
+		return nil, ErrInvalidLimit
+	}
+	value, err := locker.newValue(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	return &Semaphore{locker: locker, key: key, value: value, limit: limit}, nil
+}
+
+// Acquire acquires the semaphore if the number of holders is less than the limit,
+// or extends the TTL if the semaphore is already acquired by this holder.
+func (sem *Semaphore) Acquire(ctx context.Context, ttl time.Duration) (SemaphoreResult, error) {
+	r := SemaphoreResult{}
+	res, err := sem.locker.eval(ctx, "acquire", semacquirescr, []string{sem.locker.redisKey(sem.key)}, sem.value, sem.locker.ms(ttl), sem.limit)
+	if err != nil {
+		return r, err
+	}
+	arr, ok := res.([]interface{})
+	if !ok || len(arr) != 2 {
+		return r, ErrInvalidResponse
+	}
+	v, ok := arr[0].(int64)
+	if !ok {
+		return r, ErrInvalidResponse
+	}
+	remaining, ok := arr[1].(int64)
+	if !ok {
+		return r, ErrInvalidResponse
+	}
+	r.Result, r.Remaining = Result(v), int(remaining)
+	return r, nil
+}
+
+// Release releases the semaphore, and reports whether it was held by this holder.
+func (sem *Semaphore) Release(ctx context.Context) (bool, error) {
+	v, err := sem.locker.run(ctx, "release", semreleasescr, []string{sem.locker.redisKey(sem.key)}, sem.value)
+	if err != nil {
+		return false, err
+	}
+	return v == 1, nil
+}
@@ -0,0 +1,72 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	locker := NewLocker(client)
+	_, err = locker.NewSemaphore(key, 0)
+	require.Equal(t, ErrInvalidLimit, err)
+
+	ttl := time.Second
+	sems := make([]*Semaphore, 3)
+	for i := range sems {
+		sems[i], err = locker.NewSemaphore(key, 2)
+		require.NoError(t, err)
+	}
+
+	r, err := sems[0].Acquire(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+	require.Equal(t, 1, r.Remaining)
+
+	r, err = sems[1].Acquire(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+	require.Equal(t, 0, r.Remaining)
+
+	r, err = sems[2].Acquire(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.True(t, r.TTL() > 0 && r.TTL() <= ttl)
+	require.Equal(t, 0, r.Remaining)
+
+	r, err = sems[0].Acquire(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	ok, err := sems[1].Release(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = sems[1].Release(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	r, err = sems[2].Acquire(ctx, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+
+	pttl, err := client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 100*time.Millisecond && pttl <= ttl)
+
+	time.Sleep(200 * time.Millisecond) // the holder expires
+	r, err = sems[1].Acquire(ctx, ttl)
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+	require.Equal(t, 0, r.Remaining)
+}
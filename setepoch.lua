@@ -0,0 +1,5 @@
+if tonumber(ARGV[1]) < (tonumber(redis.call("get", KEYS[1])) or 0) then
+	return redis.error_reply("STALEEPOCH epoch is older than the current epoch")
+end
+redis.call("set", KEYS[1], ARGV[1])
+return 1
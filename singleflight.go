@@ -0,0 +1,74 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// flight is the call of applying a lock shared by concurrent callers.
+type flight struct {
+	done chan struct{}
+	lr   LockResult
+	err  error
+}
+
+// flightGroup coalesces concurrent calls of applying locks of the same key.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// WithSingleflight sets Locker.Lock to share a single Redis call among concurrent callers applying
+// the lock of the same key within the process. The caller which makes the call owns the lock if it is applied,
+// other callers get ErrHeldLocally with the result of the lock held for the TTL and without a lock,
+// and should wait for the owner to release the lock within the process. If the lock is not applied,
+// all callers get the same result, each with its own new lock.
+func WithSingleflight() Option {
+	return func(locker *Locker) {
+		locker.flights = &flightGroup{flights: make(map[string]*flight)}
+	}
+}
+
+// lockShared applies the lock of the key sharing the Redis call with concurrent callers.
+// A caller waiting for the shared call returns the error of its own ctx if ctx is done first,
+// and tries again if the shared call failed only because the ctx of the caller making it was done.
+func (locker *Locker) lockShared(ctx context.Context, key string, ttl time.Duration, lock func() (LockResult, error)) (LockResult, error) {
+	g := locker.flights
+	for {
+		g.mu.Lock()
+		if f, ok := g.flights[key]; ok {
+			g.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return LockResult{}, ctx.Err()
+			case <-f.done:
+			}
+			if errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded) {
+				continue
+			}
+			if f.err != nil {
+				return LockResult{}, f.err
+			}
+			if f.lr.OK() {
+				return LockResult{Result: Result(locker.ms(ttl))}, ErrHeldLocally
+			}
+			value, err := locker.newValue(ctx, key)
+			if err != nil {
+				return LockResult{}, err
+			}
+			return LockResult{Lock: locker.newLock(key, value), Result: f.lr.Result}, nil
+		}
+		f := &flight{done: make(chan struct{})}
+		g.flights[key] = f
+		g.mu.Unlock()
+
+		f.lr, f.err = lock()
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+		return f.lr, f.err
+	}
+}
@@ -0,0 +1,154 @@
+package locker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSingleflight(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock, WithSingleflight())
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	started := make(chan struct{})
+	release := make(chan struct{})
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Run(func(args mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+
+	n := 5
+	var wg sync.WaitGroup
+	results := make([]LockResult, n)
+	errs := make([]error, n)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], errs[0] = locker.Lock(ctx, key, ttl)
+	}()
+	<-started
+	for i := 1; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = locker.Lock(ctx, key, ttl)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond) // other callers wait for the shared call
+	close(release)
+	wg.Wait()
+
+	require.NoError(t, errs[0])
+	require.True(t, results[0].OK())
+	for i := 1; i < n; i++ {
+		require.Equal(t, ErrHeldLocally, errs[i])
+		require.False(t, results[i].OK())
+		require.Equal(t, ttl, results[i].TTL())
+		require.Equal(t, Lock{}, results[i].Lock)
+	}
+	clientMock.AssertExpectations(t)
+	require.Len(t, locker.flights.flights, 0)
+
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(42), nil)).Once()
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	clientMock.AssertExpectations(t)
+}
+
+func TestWithSingleflightNotApplied(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock, WithSingleflight())
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	started := make(chan struct{})
+	release := make(chan struct{})
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Run(func(args mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(redis.NewCmdResult(int64(42), nil)).Once()
+
+	var wg sync.WaitGroup
+	var winner, waiter LockResult
+	var winnerErr, waiterErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		winner, winnerErr = locker.Lock(ctx, key, ttl)
+	}()
+	<-started
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		waiter, waiterErr = locker.Lock(ctx, key, ttl)
+	}()
+	time.Sleep(50 * time.Millisecond) // the waiter waits for the shared call
+	close(release)
+	wg.Wait()
+
+	require.NoError(t, winnerErr)
+	require.NoError(t, waiterErr)
+	require.Equal(t, winner.Result, waiter.Result)
+	require.Equal(t, key, waiter.Key())
+	require.NotEmpty(t, waiter.Token())
+	require.NotEqual(t, winner.Token(), waiter.Token()) // the waiter gets its own lock
+	clientMock.AssertExpectations(t)
+}
+
+func TestWithSingleflightContext(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock, WithSingleflight())
+
+	key := "key"
+	ttl := time.Second
+	started := make(chan struct{})
+	release := make(chan struct{})
+	clientMock.On("EvalSha", mock.Anything, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Run(func(args mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(redis.NewCmdResult(nil, context.Canceled)).Once()
+	clientMock.On("EvalSha", mock.Anything, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	clientMock.On("EvalSha", mock.Anything, unlockscr.Hash(), []string{key}, mock.Anything).Return(redis.NewCmdResult(int64(0), nil)).Maybe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	var winnerErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, winnerErr = locker.Lock(ctx, key, ttl)
+	}()
+	<-started
+
+	// a waiter with its own ctx done returns without waiting for the shared call
+	waiterCtx, waiterCancel := context.WithCancel(context.Background())
+	waiterCancel()
+	_, err := locker.Lock(waiterCtx, key, ttl)
+	require.Equal(t, context.Canceled, err)
+
+	var lr LockResult
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lr, err = locker.Lock(context.Background(), key, ttl)
+	}()
+	time.Sleep(50 * time.Millisecond) // the waiter waits for the shared call
+	cancel()
+	close(release)
+	wg.Wait()
+
+	require.ErrorIs(t, winnerErr, context.Canceled)
+	require.NoError(t, err) // the waiter tries again instead of getting the error of the ctx of the winner
+	require.True(t, lr.OK())
+	clientMock.AssertExpectations(t)
+}
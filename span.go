@@ -0,0 +1,30 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// SpanFunc starts a span of the operation op on the lock of the key with the TTL, "lock" or "unlock",
+// e.g. a tracing span, and returns the context of the span, which is used for the operation,
+// and the function ending the span with the outcome: the flag of the lock applied or released, and the error.
+type SpanFunc func(ctx context.Context, op string, key string, ttl time.Duration) (context.Context, func(ok bool, err error))
+
+// WithSpan sets the function starting a span around each Locker.Lock, Lock.Lock and Lock.Unlock,
+// see the tracing package for OpenTelemetry spans. By default spans are not started.
+func WithSpan(fn SpanFunc) Option {
+	return func(locker *Locker) {
+		locker.span = fn
+	}
+}
+
+// endNoSpan ends no span.
+func endNoSpan(ok bool, err error) {}
+
+// startSpan starts a span of the operation op with the function set by WithSpan.
+func (locker *Locker) startSpan(ctx context.Context, op string, key string, ttl time.Duration) (context.Context, func(ok bool, err error)) {
+	if locker.span == nil {
+		return ctx, endNoSpan
+	}
+	return locker.span(ctx, op, key, ttl)
+}
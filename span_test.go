@@ -0,0 +1,65 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+type span struct {
+	op  string
+	key string
+	ttl time.Duration
+	ok  bool
+	err error
+}
+
+func TestWithSpan(t *testing.T) {
+	var spans []*span
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock, WithSpan(func(ctx context.Context, op string, key string, ttl time.Duration) (context.Context, func(ok bool, err error)) {
+		s := &span{op: op, key: key, ttl: ttl}
+		spans = append(spans, s)
+		return context.WithValue(ctx, spanKey{}, s), func(ok bool, err error) {
+			s.ok, s.err = ok, err
+		}
+	}))
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	keys := []string{key}
+	value := "value"
+	inSpan := mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Value(spanKey{}) == spans[len(spans)-1]
+	})
+	clientMock.On("EvalSha", inSpan, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	lr, err := locker.LockWithValue(ctx, key, value, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", inSpan, lockscr.Hash(), keys, value, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Once()
+	_, err = lr.Lock.Lock(ctx, ttl)
+	require.ErrorIs(t, err, e)
+
+	clientMock.On("EvalSha", inSpan, unlockscr.Hash(), keys, value).Return(redis.NewCmdResult(int64(1), nil)).Once()
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	clientMock.AssertExpectations(t)
+
+	require.Len(t, spans, 3)
+	require.Equal(t, span{op: "lock", key: key, ttl: ttl, ok: true}, *spans[0])
+	require.Equal(t, "lock", spans[1].op)
+	require.False(t, spans[1].ok)
+	require.ErrorIs(t, spans[1].err, e)
+	require.Equal(t, span{op: "unlock", key: key, ok: true}, *spans[2])
+}
@@ -0,0 +1,10 @@
+module github.com/da440dil/go-locker/tracing
+
+go 1.16
+
+require (
+	github.com/da440dil/go-locker v0.0.0-20261016015735-ace3304b8785
+	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/otel v1.3.0
+	go.opentelemetry.io/otel/trace v1.3.0
+)
@@ -0,0 +1,39 @@
+// Package tracing provides OpenTelemetry spans of lock operations. It is a separate module,
+// so the locker module does not depend on OpenTelemetry.
+package tracing
+
+import (
+	"context"
+	"time"
+
+	locker "github.com/da440dil/go-locker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracer sets locker to start a span of the tracer around each Locker.Lock, Lock.Lock and Lock.Unlock,
+// named "locker.lock" or "locker.unlock", with the lock.key attribute, lock.ttl_ms and lock.acquired attributes
+// of applying a lock, and the lock.released attribute of releasing a lock. The span status is set to error
+// and the error is recorded if the operation fails.
+func WithTracer(tracer trace.Tracer) locker.Option {
+	return locker.WithSpan(func(ctx context.Context, op string, key string, ttl time.Duration) (context.Context, func(ok bool, err error)) {
+		attrs := []attribute.KeyValue{attribute.String("lock.key", key)}
+		if op == "lock" {
+			attrs = append(attrs, attribute.Int64("lock.ttl_ms", int64(ttl/time.Millisecond)))
+		}
+		ctx, span := tracer.Start(ctx, "locker."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+		return ctx, func(ok bool, err error) {
+			if op == "lock" {
+				span.SetAttributes(attribute.Bool("lock.acquired", ok))
+			} else {
+				span.SetAttributes(attribute.Bool("lock.released", ok))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	locker "github.com/da440dil/go-locker"
+	"github.com/da440dil/go-locker/memory"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type spanMock struct {
+	trace.Span
+	name  string
+	attrs []attribute.KeyValue
+	code  codes.Code
+	errs  []error
+	ended bool
+}
+
+func (s *spanMock) SetAttributes(kv ...attribute.KeyValue) {
+	s.attrs = append(s.attrs, kv...)
+}
+
+func (s *spanMock) RecordError(err error, options ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *spanMock) SetStatus(code codes.Code, description string) {
+	s.code = code
+}
+
+func (s *spanMock) End(options ...trace.SpanEndOption) {
+	s.ended = true
+}
+
+type tracerMock struct {
+	spans []*spanMock
+}
+
+func (t *tracerMock) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	s := &spanMock{name: name, attrs: cfg.Attributes()}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestWithTracer(t *testing.T) {
+	gw := memory.New(0)
+	defer gw.Close()
+	tracer := &tracerMock{}
+	lkr := locker.NewLockerGateway(gw, WithTracer(tracer))
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	lr, err := lkr.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = lr.Lock.Lock(cctx, ttl)
+	require.Equal(t, context.Canceled, err)
+
+	require.Len(t, tracer.spans, 3)
+	s := tracer.spans[0]
+	require.Equal(t, "locker.lock", s.name)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("lock.key", key),
+		attribute.Int64("lock.ttl_ms", 1000),
+		attribute.Bool("lock.acquired", true),
+	}, s.attrs)
+	require.Equal(t, codes.Unset, s.code)
+	require.True(t, s.ended)
+
+	s = tracer.spans[1]
+	require.Equal(t, "locker.unlock", s.name)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("lock.key", key),
+		attribute.Bool("lock.released", true),
+	}, s.attrs)
+	require.True(t, s.ended)
+
+	s = tracer.spans[2]
+	require.Equal(t, "locker.lock", s.name)
+	require.Equal(t, codes.Error, s.code)
+	require.Equal(t, []error{context.Canceled}, s.errs)
+	require.True(t, s.ended)
+}
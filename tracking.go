@@ -0,0 +1,74 @@
+package locker
+
+import (
+	"sync"
+	"time"
+)
+
+// heldLock identifies a lock held by the locker.
+type heldLock struct {
+	key   string
+	value string
+}
+
+// tracker tracks locks held by the locker.
+type tracker struct {
+	mu   sync.Mutex
+	held map[heldLock]time.Time
+}
+
+// WithTracking sets locker to track locks it holds, see Locker.HeldCount.
+func WithTracking() Option {
+	return func(locker *Locker) {
+		locker.tracker = &tracker{held: make(map[heldLock]time.Time)}
+	}
+}
+
+// HeldCount returns the number of locks the locker holds, which are applied or extended
+// and neither released nor expired according to the local clock.
+// Requires tracking enabled with WithTracking, otherwise returns 0.
+func (locker *Locker) HeldCount() int {
+	t := locker.tracker
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for l, deadline := range t.held {
+		if !deadline.After(now) {
+			delete(t.held, l)
+		}
+	}
+	return len(t.held)
+}
+
+// track tracks the lock after applying or extending it.
+func (locker *Locker) track(lock *Lock, r Result, ttl time.Duration, start time.Time) {
+	t := locker.tracker
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l := heldLock{key: lock.key, value: lock.value}
+	if r.OK() {
+		t.held[l] = start.Add(ttl)
+	} else {
+		delete(t.held, l)
+	}
+}
+
+// untrack stops tracking the lock after releasing it.
+func (locker *Locker) untrack(lock *Lock) {
+	t := locker.tracker
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.held, heldLock{key: lock.key, value: lock.value})
+}
@@ -0,0 +1,69 @@
+package locker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeldCount(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	keys := []string{"key1", "key2", "key3"}
+	err := client.Del(ctx, keys...).Err()
+	require.NoError(t, err)
+
+	ttl := 200 * time.Millisecond
+	locker := NewLocker(client)
+	_, err = locker.Lock(ctx, keys[0], ttl)
+	require.NoError(t, err)
+	require.Equal(t, 0, locker.HeldCount())
+	err = client.Del(ctx, keys[0]).Err()
+	require.NoError(t, err)
+
+	locker = NewLocker(client, WithTracking())
+	var wg sync.WaitGroup
+	results := make([]LockResult, len(keys))
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			lr, err := locker.Lock(ctx, key, ttl)
+			require.NoError(t, err)
+			require.True(t, lr.OK())
+			results[i] = lr
+		}(i, key)
+	}
+	wg.Wait()
+	require.Equal(t, 3, locker.HeldCount())
+
+	lr, err := locker.Lock(ctx, keys[0], ttl)
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	require.Equal(t, 3, locker.HeldCount())
+
+	ok, err := results[0].Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 2, locker.HeldCount())
+
+	r, err := results[1].Lock.Lock(ctx, 2*ttl)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+
+	time.Sleep(ttl + 50*time.Millisecond) // wait for the ttl of the key3 is over
+	require.Equal(t, 1, locker.HeldCount())
+
+	err = client.Set(ctx, keys[1], "value", ttl).Err()
+	require.NoError(t, err)
+	r, err = results[1].Lock.Lock(ctx, ttl)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.Equal(t, 0, locker.HeldCount())
+}
@@ -0,0 +1,82 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// PreparedLock is a set of locks applied with a short TTL by Locker.Prepare.
+type PreparedLock struct {
+	locks []Lock
+}
+
+// PrepareResult contains prepared locks and result of applying them.
+type PrepareResult struct {
+	*PreparedLock
+	// Result of applying the lock of the first key which is held by someone else,
+	// or the result of applying the lock of the last key if all locks are applied.
+	Result
+	// Key is the key of the lock held by someone else if the locks are not applied.
+	Key string
+}
+
+// Prepare applies locks of the keys with the same value and a short TTL, one by one, to coordinate
+// an operation with many resources in two phases: Prepare applies locks for a short time, then
+// PreparedLock.Commit extends them for the time of the operation, or PreparedLock.Abort releases them.
+// It avoids holding long locks while applying locks of other keys may fail.
+// If any lock is held by someone else, the locks applied before are released, waiting no longer than the TTL,
+// as the locks expire by then anyway. Returns ErrNoKeys if there are no keys.
+func (locker *Locker) Prepare(ctx context.Context, keys []string, ttl time.Duration) (PrepareResult, error) {
+	r := PrepareResult{}
+	if len(keys) == 0 {
+		return r, ErrNoKeys
+	}
+	value, err := locker.newValue(ctx, keys...)
+	if err != nil {
+		return r, err
+	}
+	pl := &PreparedLock{locks: make([]Lock, 0, len(keys))}
+	for _, key := range keys {
+		lock := locker.newLock(key, value)
+		r.Result, _, err = lock.acquire(ctx, ttl)
+		if err != nil || !r.OK() {
+			actx, cancel := context.WithTimeout(context.Background(), ttl)
+			if aerr := pl.Abort(actx); err == nil {
+				err = aerr
+			}
+			cancel()
+			r.Key = key
+			return r, err
+		}
+		pl.locks = append(pl.locks, lock)
+	}
+	r.PreparedLock = pl
+	return r, nil
+}
+
+// Commit extends the prepared locks to the TTL. If any lock is lost, releases all locks
+// and returns ErrLockLost.
+func (pl *PreparedLock) Commit(ctx context.Context, ttl time.Duration) error {
+	for _, lock := range pl.locks {
+		r, err := lock.extend(ctx, ttl)
+		if err == nil && !r.Extended() {
+			err = ErrLockLost
+		}
+		if err != nil {
+			_ = pl.Abort(ctx)
+			return err
+		}
+	}
+	return nil
+}
+
+// Abort releases the prepared locks, it is also used to release committed locks after the operation.
+func (pl *PreparedLock) Abort(ctx context.Context) error {
+	var err error
+	for _, lock := range pl.locks {
+		if _, uerr := lock.Unlock(ctx); uerr != nil && err == nil {
+			err = uerr
+		}
+	}
+	return err
+}
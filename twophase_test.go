@@ -0,0 +1,90 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepare(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	keys := []string{"key1", "key2", "key3"}
+	err := client.Del(ctx, keys...).Err()
+	require.NoError(t, err)
+
+	shortTTL := 100 * time.Millisecond
+	fullTTL := 10 * time.Second
+	locker := NewLocker(client)
+
+	pr, err := locker.Prepare(ctx, keys, shortTTL)
+	require.NoError(t, err)
+	require.True(t, pr.OK())
+	for _, key := range keys {
+		pttl, err := client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.True(t, pttl <= shortTTL)
+	}
+
+	pr2, err := locker.Prepare(ctx, []string{"key0", "key2"}, shortTTL)
+	require.NoError(t, err)
+	require.False(t, pr2.OK())
+	require.Equal(t, "key2", pr2.Key)
+	require.True(t, pr2.TTL() > 0 && pr2.TTL() <= shortTTL)
+	require.Nil(t, pr2.PreparedLock)
+	err = client.Get(ctx, "key0").Err()
+	require.Equal(t, redis.Nil, err)
+
+	err = pr.Commit(ctx, fullTTL)
+	require.NoError(t, err)
+	for _, key := range keys {
+		pttl, err := client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.True(t, pttl > shortTTL)
+	}
+
+	err = pr.Abort(ctx)
+	require.NoError(t, err)
+	n, err := client.Exists(ctx, keys...).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	pr, err = locker.Prepare(ctx, keys, shortTTL)
+	require.NoError(t, err)
+	require.True(t, pr.OK())
+	err = client.Set(ctx, keys[1], "value", fullTTL).Err()
+	require.NoError(t, err)
+	err = pr.Commit(ctx, fullTTL)
+	require.Equal(t, ErrLockLost, err)
+	n, err = client.Exists(ctx, keys...).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+}
+
+func TestPrepareAbortTimeout(t *testing.T) {
+	ctx := context.Background()
+	ttl := 100 * time.Millisecond
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock)
+
+	_, err := locker.Prepare(ctx, nil, ttl)
+	require.Equal(t, ErrNoKeys, err)
+
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{"key1"}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{"key2"}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(42), nil)).Once()
+	clientMock.On("EvalSha", mock.Anything, unlockscr.Hash(), []string{"key1"}, mock.Anything).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(redis.NewCmdResult(nil, context.DeadlineExceeded)).Once()
+	start := time.Now()
+	pr, err := locker.Prepare(ctx, []string{"key1", "key2"}, ttl)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, "key2", pr.Key)
+	require.True(t, time.Since(start) < 2*ttl)
+	clientMock.AssertExpectations(t)
+}
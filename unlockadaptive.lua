@@ -0,0 +1,5 @@
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	redis.call("del", KEYS[2])
+	return redis.call("del", KEYS[1])
+end
+return 0
@@ -0,0 +1,8 @@
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	redis.call("del", KEYS[1])
+	redis.call("lpush", KEYS[2], 1)
+	redis.call("ltrim", KEYS[2], 0, 0)
+	redis.call("pexpire", KEYS[2], ARGV[2])
+	return 1
+end
+return 0
\ No newline at end of file
@@ -0,0 +1,7 @@
+if redis.call("hget", KEYS[1], "token") == ARGV[1] then
+	if redis.call("hincrby", KEYS[1], "count", -1) <= 0 then
+		redis.call("del", KEYS[1])
+	end
+	return 1
+end
+return 0
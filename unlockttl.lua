@@ -0,0 +1,11 @@
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	local ttl = redis.call("pttl", KEYS[1])
+	redis.call("del", KEYS[1])
+	if KEYS[2] then
+		redis.call("lpush", KEYS[2], 1)
+		redis.call("ltrim", KEYS[2], 0, 0)
+		redis.call("pexpire", KEYS[2], ARGV[2])
+	end
+	return ttl
+end
+return -2
@@ -0,0 +1,65 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed extenduntil.lua
+var extenduntilsrc string
+var extenduntilscr = redis.NewScript(extenduntilsrc)
+
+//go:embed extendepochuntil.lua
+var extendepochuntilsrc string
+var extendepochuntilscr = redis.NewScript(extendepochuntilsrc)
+
+//go:embed extendgtuntil.lua
+var extendgtuntilsrc string
+var extendgtuntilscr = redis.NewScript(extendgtuntilsrc)
+
+//go:embed extendreentrantuntil.lua
+var extendreentrantuntilsrc string
+var extendreentrantuntilscr = redis.NewScript(extendreentrantuntilsrc)
+
+// extendUntilScript returns the script extending a lock until the deadline, the same variant as extendScript.
+func (locker *Locker) extendUntilScript() *redis.Script {
+	switch locker.extendScript() {
+	case extendreentrantscr:
+		return extendreentrantuntilscr
+	case extendepochscr:
+		return extendepochuntilscr
+	case extendgtscr:
+		return extendgtuntilscr
+	}
+	return extenduntilscr
+}
+
+// ExtendUntil extends the lock until the deadline if the lock is held by the same owner, and reports whether it is.
+// The TTL is computed from the deadline by Redis using its own clock, so it does not depend on the client clock
+// being in sync. Returns false without extending the lock if the deadline is over.
+// The lock is extended the same way as by Lock.Lock, e.g. respecting WithReentrant and WithEpochKey.
+func (lock *Lock) ExtendUntil(ctx context.Context, deadline time.Time) (bool, error) {
+	start := time.Now()
+	keys := lock.locker.lockKeys(lock.locker.redisKey(lock.key))
+	v, err := lock.locker.run(ctx, "extend", lock.locker.extendUntilScript(), keys, lock.value, deadline.UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return false, err
+	}
+	r := Result(v)
+	lock.locker.track(lock, r, deadline.Sub(start), start)
+	return r.Extended(), nil
+}
+
+// LockUntil applies the lock or extends the lock TTL as Lock does, with the TTL until the deadline
+// measured by the client clock. Returns ErrDeadlinePassed if less than a millisecond is left until the deadline,
+// as the TTL of a lock is in milliseconds. Use ExtendUntil to extend a lock using the Redis clock.
+func (lock *Lock) LockUntil(ctx context.Context, deadline time.Time) (Result, error) {
+	ttl := time.Until(deadline)
+	if ttl < time.Millisecond {
+		return 0, ErrDeadlinePassed
+	}
+	return lock.Lock(ctx, ttl)
+}
@@ -0,0 +1,127 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtendUntil(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client)
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	ok, err := lr.ExtendUntil(ctx, time.Now().Add(10*time.Second))
+	require.NoError(t, err)
+	require.True(t, ok)
+	pttl, err := client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 9*time.Second && pttl < 11*time.Second, pttl) // the clocks of Redis and the client may differ a bit
+
+	ok, err = lr.ExtendUntil(ctx, time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	require.False(t, ok)
+	pttl, err = client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 9*time.Second)
+
+	lr2, err := locker.LockWithValue(ctx, key, "token", ttl)
+	require.NoError(t, err)
+	ok, err = lr2.ExtendUntil(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = lr.Unlock(ctx)
+	require.NoError(t, err)
+}
+
+func TestLockUntil(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	locker := NewLocker(client)
+	lock, err := locker.NewLock(key)
+	require.NoError(t, err)
+
+	_, err = lock.LockUntil(ctx, time.Now().Add(-time.Second))
+	require.Equal(t, ErrDeadlinePassed, err)
+	_, err = lock.LockUntil(ctx, time.Now())
+	require.Equal(t, ErrDeadlinePassed, err)
+
+	ttl := time.Second
+	r, err := lock.LockUntil(ctx, time.Now().Add(ttl))
+	require.NoError(t, err)
+	require.True(t, r.Acquired())
+	pttl, err := client.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > ttl/2 && pttl <= ttl)
+
+	r, err = lock.LockUntil(ctx, time.Now().Add(2*ttl))
+	require.NoError(t, err)
+	require.True(t, r.Extended())
+
+	ok, err := lock.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestExtendUntilVariants(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	epochKey := "epoch"
+	err := client.Del(ctx, key, epochKey).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	for _, option := range []Option{WithReentrant(), WithGrowOnlyTTL()} {
+		locker := NewLocker(client, option)
+		lr, err := locker.Lock(ctx, key, ttl)
+		require.NoError(t, err)
+		require.True(t, lr.OK())
+
+		ok, err := lr.ExtendUntil(ctx, time.Now().Add(10*time.Second))
+		require.NoError(t, err)
+		require.True(t, ok)
+		pttl, err := client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		require.True(t, pttl > 9*time.Second, pttl)
+
+		ok, err = lr.Unlock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	locker := NewLocker(client, WithEpochKey(epochKey))
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	err = client.Set(ctx, epochKey, 1<<40, 0).Err()
+	require.NoError(t, err)
+	_, err = lr.ExtendUntil(ctx, time.Now().Add(10*time.Second))
+	require.ErrorIs(t, err, ErrStaleEpoch)
+
+	err = client.Del(ctx, key, epochKey).Err()
+	require.NoError(t, err)
+}
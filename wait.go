@@ -0,0 +1,171 @@
+package locker
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed unlocknotify.lua
+var unlocknotifysrc string
+var unlocknotifyscr = redis.NewScript(unlocknotifysrc)
+
+// maxWait is the maximum duration of a single wait for the lock to be released.
+const maxWait = time.Second
+
+// minWait is the minimum duration of a single wait, so a lock which is about to expire is not polled without a pause.
+const minWait = time.Millisecond
+
+// errorRetryDelay is the delay of retrying to apply a lock after a retryable error.
+const errorRetryDelay = 100 * time.Millisecond
+
+// notifyTTL is the TTL of a notification that the lock is released.
+const notifyTTL = time.Second
+
+// blockingClient is redis client interface for blocking list commands.
+type blockingClient interface {
+	BRPop(ctx context.Context, timeout time.Duration, keys ...string) *redis.StringSliceCmd
+}
+
+// LockWait creates and applies new lock, if the lock is held by someone else
+// waits for it to be released and tries again until ctx is done.
+// Locks created by LockWait push a notification to the list with key "<key>:notify" on Unlock,
+// waiters block on this list using BRPOP, so no keyspace notifications config is required.
+// A waiter also retries after the TTL of the held lock is over, so locks released
+// without notification (e.g. expired) delay waiters no longer than their TTL.
+// Errors for which the function set by WithRetryableError returns true are retried after a short delay,
+// by default only transient connection errors are retried.
+// With WithRetryMaxTTL LockWait does not wait for a lock with the TTL greater than the max TTL.
+// Waiting is bounded by ctx deadline, cancellation of ctx without deadline is noticed within a second.
+// If the client does not implement BRPop, waiters just sleep until the held lock TTL is over.
+func (locker *Locker) LockWait(ctx context.Context, key string, ttl time.Duration) (LockResult, error) {
+	r := LockResult{}
+	value, err := locker.newValue(ctx, key)
+	if err != nil {
+		return r, err
+	}
+	r.Lock = locker.newLock(key, value)
+	r.notify = true
+	for {
+		r.Result, r.Waiters, err = r.Lock.acquire(ctx, ttl)
+		if err == nil && !r.OK() {
+			if locker.retryMaxTTL > 0 && r.TTL() > locker.retryMaxTTL {
+				return r, nil
+			}
+			if err = locker.wait(ctx, key, r.TTL()); err == nil {
+				continue
+			}
+		} else if err != nil && ctx.Err() == nil && locker.retryable(err) {
+			if err = sleep(ctx, errorRetryDelay); err == nil {
+				continue
+			}
+		}
+		if err != nil && locker.queue {
+			r.Lock.dequeue()
+		}
+		return r, err
+	}
+}
+
+// LockWithRetry creates and applies new lock, if the lock is held by someone else tries again after a delay
+// set by WithRetryDelay and WithRetryJitter, until the lock is applied, attempts set by WithRetryCount are over,
+// or ctx is done. Unlike LockWait it does not wait for notifications,
+// so it works with any lock of the key. If ctx deadline is exceeded the result of the last try is returned
+// without error, so its TTL is the TTL of the held lock, if ctx is canceled the error of ctx is returned.
+// Errors for which the function set by WithRetryableError returns true are retried the same way,
+// if attempts are over or ctx deadline is exceeded after such an error, the error is returned.
+// If ctx deadline is exceeded during a try, the result of the previous try is returned.
+func (locker *Locker) LockWithRetry(ctx context.Context, key string, ttl time.Duration) (LockResult, error) {
+	var last LockResult
+	var lastErr error
+	for i := 0; ; i++ {
+		r, err := locker.Lock(ctx, key, ttl)
+		if err != nil && i > 0 && ctx.Err() == context.DeadlineExceeded {
+			return last, lastErr
+		}
+		if err != nil && (ctx.Err() != nil || !locker.retryable(err)) {
+			return r, err
+		}
+		last, lastErr = r, err
+		if (err == nil && r.OK()) || (locker.retryLimited && i >= locker.retryCount) {
+			return r, err
+		}
+		if serr := sleep(ctx, newDelay(locker.retryDelay, locker.retryJitter)); serr != nil {
+			if serr == context.DeadlineExceeded {
+				return r, err
+			}
+			return r, serr
+		}
+	}
+}
+
+// LockWithTimeout creates and applies new lock waiting for it to be released as LockWait does,
+// but no longer than acquireTimeout. The acquire timeout bounds waiting, and the ttl is the TTL of the lock.
+// If the acquire timeout is over the result of the last try to apply the lock is returned without error.
+func (locker *Locker) LockWithTimeout(ctx context.Context, key string, acquireTimeout time.Duration, ttl time.Duration) (LockResult, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, acquireTimeout)
+	defer cancel()
+
+	r, err := locker.LockWait(waitCtx, key, ttl)
+	if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+		err = nil
+	}
+	return r, err
+}
+
+// wait waits for notification that the lock is released, but no longer than ttl.
+func (locker *Locker) wait(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl == 0 {
+		ttl = minWait
+	}
+	if ttl < 0 || ttl > maxWait {
+		ttl = maxWait
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, ttl)
+	defer cancel()
+
+	client, ok := locker.client.(blockingClient)
+	if !ok {
+		<-waitCtx.Done()
+		return ctx.Err()
+	}
+	err := client.BRPop(waitCtx, maxWait, notifyKey(locker.redisKey(key))).Err()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == nil || err == redis.Nil || waitCtx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// sleep waits for the duration or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isTransient reports whether the error is a transient connection error.
+func isTransient(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// notifyKey creates Redis key of the list to push notifications that the lock is released.
+func notifyKey(key string) string {
+	return key + ":notify"
+}
@@ -0,0 +1,328 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockWait(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key, notifyKey(key)).Err()
+	require.NoError(t, err)
+
+	ttl := 10 * time.Second
+	locker := NewLocker(client)
+
+	lr1, err := locker.LockWait(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr1.OK())
+
+	ctx1, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	lr2, err := locker.LockWait(ctx1, key, ttl)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.False(t, lr2.OK())
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ok, err := lr1.Unlock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}()
+
+	ctx2, cancel := context.WithTimeout(ctx, ttl/2)
+	defer cancel()
+	start := time.Now()
+	lr2, err = locker.LockWait(ctx2, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr2.OK())
+	require.True(t, time.Since(start) < time.Second)
+
+	ok, err := lr2.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	n, err := client.Exists(ctx, notifyKey(key)).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	pttl, err := client.PTTL(ctx, notifyKey(key)).Result()
+	require.NoError(t, err)
+	require.True(t, pttl > 0 && pttl <= notifyTTL)
+}
+
+func TestLockWaitWithoutBlockingClient(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	key := "key"
+	ttl := time.Second
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(30)), nil))
+
+	start := time.Now()
+	lr, err := locker.LockWait(ctx, key, ttl)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.False(t, lr.OK())
+	require.True(t, time.Since(start) < time.Second)
+	require.True(t, len(clientMock.Calls) > 1)
+}
+
+func TestLockWaitZeroTTL(t *testing.T) {
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	key := "key"
+	ttl := time.Second
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(interface{}(int64(0)), nil))
+
+	_, err := locker.LockWait(ctx, key, ttl)
+	require.Equal(t, context.DeadlineExceeded, err)
+	n := len(clientMock.Calls)
+	require.True(t, n > 1 && n <= 50, "lock calls %d", n) // waits at least a millisecond between calls
+}
+
+func TestLockWithTimeout(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key, notifyKey(key)).Err()
+	require.NoError(t, err)
+
+	ttl := 10 * time.Second
+	locker := NewLocker(client)
+
+	lr1, err := locker.LockWithTimeout(ctx, key, 100*time.Millisecond, ttl)
+	require.NoError(t, err)
+	require.True(t, lr1.OK())
+
+	start := time.Now()
+	lr2, err := locker.LockWithTimeout(ctx, key, 100*time.Millisecond, ttl)
+	require.NoError(t, err)
+	require.False(t, lr2.OK())
+	require.True(t, lr2.TTL() > 0 && lr2.TTL() <= ttl)
+	require.True(t, time.Since(start) < time.Second)
+
+	ctx1, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = locker.LockWithTimeout(ctx1, key, 100*time.Millisecond, ttl)
+	require.ErrorIs(t, err, context.Canceled)
+
+	ok, err := lr1.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestLockWaitRetryableError(t *testing.T) {
+	key := "key"
+	ttl := time.Second
+	ctx := context.Background()
+	e := errors.New("redis error")
+
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock)
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, io.EOF)).Once()
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Once()
+	_, err := locker.LockWait(ctx, key, ttl)
+	require.ErrorIs(t, err, e)
+	clientMock.AssertExpectations(t)
+
+	clientMock = &ClientMock{}
+	locker = NewLocker(clientMock, WithRetryableError(func(err error) bool {
+		return errors.Is(err, e)
+	}))
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Once()
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	lr, err := locker.LockWait(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	clientMock.AssertExpectations(t)
+
+	ctx1, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	clientMock.On("EvalSha", ctx1, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e))
+	_, err = locker.LockWait(ctx1, key, ttl)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestLockWaitRetryMaxTTL(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client, WithRetryMaxTTL(ttl))
+
+	err = client.Set(ctx, key, "value", 10*time.Second).Err()
+	require.NoError(t, err)
+	start := time.Now()
+	lr, err := locker.LockWait(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	require.True(t, lr.TTL() > ttl)
+	require.True(t, time.Since(start) < ttl)
+
+	err = client.Set(ctx, key, "value", ttl).Err()
+	require.NoError(t, err)
+	lr, err = locker.LockWait(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+}
+
+func TestWithBlocking(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key, notifyKey(key)).Err()
+	require.NoError(t, err)
+
+	ttl := 5 * time.Second
+	locker := NewLocker(client, WithBlocking())
+
+	lr, err := locker.Lock(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	acquired := make(chan time.Time, 1)
+	go func() {
+		lr, err := locker.LockWait(ctx, key, ttl)
+		if err == nil && lr.OK() {
+			acquired <- time.Now()
+			_, _ = lr.Unlock(ctx)
+		}
+	}()
+
+	time.Sleep(100 * time.Millisecond) // the waiter is blocked
+	start := time.Now()
+	ok, err := lr.Unlock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	select {
+	case at := <-acquired:
+		require.True(t, at.Sub(start) < 500*time.Millisecond)
+	case <-time.After(maxWait):
+		t.Fatal("the waiter is not woken")
+	}
+}
+
+func TestLockWithRetry(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client)
+
+	err = client.Set(ctx, key, "value", ttl).Err()
+	require.NoError(t, err)
+	lr, err := locker.LockWithRetry(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+
+	err = client.Set(ctx, key, "value", time.Minute).Err()
+	require.NoError(t, err)
+	ctx1, cancel1 := context.WithTimeout(ctx, ttl)
+	defer cancel1()
+	lr, err = locker.LockWithRetry(ctx1, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	require.True(t, lr.TTL() > ttl)
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+	time.AfterFunc(ttl, cancel2)
+	start := time.Now()
+	_, err = locker.LockWithRetry(ctx2, key, ttl)
+	require.Equal(t, context.Canceled, err)
+	require.True(t, time.Since(start) < ttl+defaultRetryDelay)
+
+	err = client.Del(ctx, key).Err()
+	require.NoError(t, err)
+}
+
+func TestIsTransient(t *testing.T) {
+	require.True(t, isTransient(io.EOF))
+	require.True(t, isTransient(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	require.False(t, isTransient(errors.New("redis error")))
+	require.False(t, isTransient(ErrKeyNameClash))
+}
+
+func TestLockWithRetryOptions(t *testing.T) {
+	clientMock := &ClientMock{}
+	delay := 20 * time.Millisecond
+	locker := NewLocker(clientMock, WithRetryCount(3), WithRetryDelay(delay), WithRetryJitter(0))
+
+	ctx := context.Background()
+	key := "key"
+	ttl := time.Second
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(42), nil)).Times(4)
+
+	start := time.Now()
+	lr, err := locker.LockWithRetry(ctx, key, ttl)
+	require.NoError(t, err)
+	require.False(t, lr.OK())
+	require.True(t, time.Since(start) >= 3*delay)
+	clientMock.AssertExpectations(t)
+
+	for _, option := range []Option{WithRetryCount(-1), WithRetryDelay(-delay), WithRetryJitter(-delay)} {
+		_, err = NewLockerContext(ctx, clientMock, option)
+		require.Equal(t, ErrInvalidOption, err)
+	}
+}
+
+func TestLockWithRetryRetryableError(t *testing.T) {
+	key := "key"
+	ttl := time.Second
+	ctx := context.Background()
+	e := errors.New("redis error")
+
+	clientMock := &ClientMock{}
+	locker := NewLocker(clientMock, WithRetryDelay(time.Millisecond), WithRetryJitter(0))
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, io.EOF)).Once()
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(int64(-3), nil)).Once()
+	lr, err := locker.LockWithRetry(ctx, key, ttl)
+	require.NoError(t, err)
+	require.True(t, lr.OK())
+	clientMock.AssertExpectations(t)
+
+	clientMock = &ClientMock{}
+	locker = NewLocker(clientMock, WithRetryDelay(time.Millisecond), WithRetryJitter(0))
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Once()
+	_, err = locker.LockWithRetry(ctx, key, ttl)
+	require.ErrorIs(t, err, e)
+	clientMock.AssertExpectations(t)
+
+	clientMock = &ClientMock{}
+	locker = NewLocker(clientMock, WithRetryCount(2), WithRetryDelay(time.Millisecond), WithRetryJitter(0), WithRetryableError(func(err error) bool {
+		return errors.Is(err, e)
+	}))
+	clientMock.On("EvalSha", ctx, lockscr.Hash(), []string{key}, mock.Anything, int(ttl/time.Millisecond)).Return(redis.NewCmdResult(nil, e)).Times(3)
+	_, err = locker.LockWithRetry(ctx, key, ttl)
+	require.ErrorIs(t, err, e)
+	clientMock.AssertExpectations(t)
+}
@@ -0,0 +1,110 @@
+package locker
+
+import (
+	"context"
+	"time"
+)
+
+// WorkerLock is a lock held by a worker, it is extended in the background until released.
+type WorkerLock struct {
+	lock   Lock
+	ttl    time.Duration
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// NewWorkerLock applies the lock waiting for it to be released as LockWait does,
+// then extends the lock in the background every RefreshInterval(ttl), randomly earlier with WithRefreshJitter,
+// until ctx is done or Release is called, after that releases the lock.
+// It replaces applying, extending and releasing a lock by each worker of a pool manually:
+//
+//	wl, err := lkr.NewWorkerLock(ctx, key, ttl)
+//	if err != nil {
+//		return err
+//	}
+//	defer wl.Release()
+//	work(wl.Context())
+func (locker *Locker) NewWorkerLock(ctx context.Context, key string, ttl time.Duration) (*WorkerLock, error) {
+	lr, err := locker.LockWait(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	wctx, cancel := context.WithCancel(ctx)
+	wl := &WorkerLock{
+		lock:   lr.Lock,
+		ttl:    ttl,
+		ctx:    wctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go wl.refresh()
+	return wl, nil
+}
+
+// LockWhileOpen applies the lock and extends it in the background as NewWorkerLock does,
+// until the done channel is closed, e.g. the shutdown channel of a server, then releases the lock.
+// The lock is also released if ctx is done or Release is called, and the background goroutine exits
+// when the lock is released or lost.
+func (locker *Locker) LockWhileOpen(ctx context.Context, key string, ttl time.Duration, done <-chan struct{}) (*WorkerLock, error) {
+	wl, err := locker.NewWorkerLock(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		select {
+		case <-done:
+			wl.cancel()
+		case <-wl.done:
+		}
+	}()
+	return wl, nil
+}
+
+// Context returns the context which is done when the lock is lost, released or parent context is done.
+func (wl *WorkerLock) Context() context.Context {
+	return wl.ctx
+}
+
+// Done returns the channel which is closed when the lock is lost or released.
+func (wl *WorkerLock) Done() <-chan struct{} {
+	return wl.done
+}
+
+// Release stops extending the lock and releases it, it is safe to call Release more than once.
+// Returns ErrLockLost if the lock was lost before being released.
+func (wl *WorkerLock) Release() error {
+	wl.cancel()
+	<-wl.done
+	return wl.err
+}
+
+func (wl *WorkerLock) refresh() {
+	defer close(wl.done)
+	timer := time.NewTimer(wl.lock.locker.refreshInterval(wl.ttl))
+	defer timer.Stop()
+	for {
+		select {
+		case <-wl.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), wl.ttl)
+			defer cancel()
+			_, wl.err = wl.lock.Unlock(ctx)
+			return
+		case <-timer.C:
+			timer.Reset(wl.lock.locker.refreshInterval(wl.ttl))
+			r, err := wl.lock.refresh(wl.ctx, wl.ttl)
+			if err == nil && !r.Extended() {
+				wl.err = ErrLockLost
+				wl.lock.locker.lost(wl.lock.key)
+				wl.cancel()
+				if r.Acquired() {
+					ctx, cancel := context.WithTimeout(context.Background(), wl.ttl)
+					defer cancel()
+					_, _ = wl.lock.Unlock(ctx)
+				}
+				return
+			}
+		}
+	}
+}
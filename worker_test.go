@@ -0,0 +1,136 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerLock(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 200 * time.Millisecond
+	locker := NewLocker(client)
+
+	wl, err := locker.NewWorkerLock(ctx, key, ttl)
+	require.NoError(t, err)
+
+	time.Sleep(2 * ttl) // the lock is extended in the background
+
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, wl.lock.value, v)
+	require.NoError(t, wl.Context().Err())
+
+	require.NoError(t, wl.Release())
+	require.NoError(t, wl.Release())
+	require.Error(t, wl.Context().Err())
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+
+	ctx1, cancel := context.WithCancel(ctx)
+	wl, err = locker.NewWorkerLock(ctx1, key, ttl)
+	require.NoError(t, err)
+	cancel()
+	<-wl.Done()
+	require.NoError(t, wl.Release())
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+
+	wl, err = locker.NewWorkerLock(ctx, key, ttl)
+	require.NoError(t, err)
+	err = client.Set(ctx, key, "value", ttl).Err()
+	require.NoError(t, err)
+	select {
+	case <-wl.Done():
+	case <-time.After(ttl):
+		t.Fatal("the lock is not lost")
+	}
+	require.Error(t, wl.Context().Err())
+	require.Equal(t, ErrLockLost, wl.Release())
+	v, err = client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, "value", v)
+}
+
+func TestWithOnLost(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	lost := make(chan string, 2)
+	locker := NewLocker(client, WithOnLost(func(key string) {
+		lost <- key
+	}))
+
+	wl, err := locker.NewWorkerLock(ctx, key, ttl)
+	require.NoError(t, err)
+	time.Sleep(ttl)
+	require.NoError(t, wl.Release())
+	require.Len(t, lost, 0)
+
+	wl, err = locker.NewWorkerLock(ctx, key, ttl)
+	require.NoError(t, err)
+	err = client.Set(ctx, key, "value", 10*ttl).Err()
+	require.NoError(t, err)
+	<-wl.Done()
+	require.Equal(t, ErrLockLost, wl.Release())
+	time.Sleep(ttl)
+	require.Len(t, lost, 1)
+	require.Equal(t, key, <-lost)
+	err = client.Del(ctx, key).Err()
+	require.NoError(t, err)
+}
+
+func TestLockWhileOpen(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := 100 * time.Millisecond
+	locker := NewLocker(client)
+
+	done := make(chan struct{})
+	wl, err := locker.LockWhileOpen(ctx, key, ttl, done)
+	require.NoError(t, err)
+
+	time.Sleep(2 * ttl) // the lock is extended in the background
+
+	v, err := client.Get(ctx, key).Result()
+	require.NoError(t, err)
+	require.Equal(t, wl.lock.value, v)
+
+	close(done)
+	select {
+	case <-wl.Done():
+	case <-time.After(ttl):
+		t.Fatal("the lock is not released")
+	}
+	require.NoError(t, wl.Release())
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+
+	wl, err = locker.LockWhileOpen(ctx, key, ttl, make(chan struct{}))
+	require.NoError(t, err)
+	require.NoError(t, wl.Release())
+	err = client.Get(ctx, key).Err()
+	require.Equal(t, redis.Nil, err)
+}